@@ -0,0 +1,191 @@
+package timertxt
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvHeader is the header row written by WriteCSV.
+var csvHeader = []string{"Id", "StartDate", "FinishDate", "Duration", "Finished", "Notes", "Projects", "Contexts"}
+
+// csvListSeparator joins multiple Projects or Contexts in a single CSV field.
+const csvListSeparator = " "
+
+// CSVOptions controls how WriteCSVWithOptions writes a TimerList, and how LoadCSVWithOptions reads it back.
+type CSVOptions struct {
+	Columns       []string // Columns, in order, from the WriteCSV header. All columns when empty
+	Comma         rune     // Field delimiter, ',' when zero. Use '\t' for TSV
+	OmitHeader    bool     // No header row, the fields are in the order of Columns
+	ListSeparator string   // Joins multiple Projects or Contexts in a single field, a space when empty
+}
+
+// WriteCSV writes the TimerList to w as CSV, with a header row and one row per timer.
+// Durations are written in hours, and Projects and Contexts are space separated.
+// There are no columns for the Priority and additional tags of a timer, so they are not written.
+func (timerlist *TimerList) WriteCSV(w io.Writer) error {
+	return timerlist.WriteCSVWithOptions(w, CSVOptions{})
+}
+
+// WriteCSVWithOptions writes the TimerList to w as CSV like WriteCSV, as configured by opts.
+// Returns an error if opts.Columns has a column that WriteCSV doesn't write.
+func (timerlist *TimerList) WriteCSVWithOptions(w io.Writer, opts CSVOptions) error {
+	columns := opts.Columns
+	if len(columns) == 0 {
+		columns = csvHeader
+	}
+	separator := opts.ListSeparator
+	if separator == "" {
+		separator = csvListSeparator
+	}
+	for _, c := range columns {
+		if _, ok := csvField(Timer{}, c, separator); !ok {
+			return fmt.Errorf("unknown CSV column: %s", c)
+		}
+	}
+	writer := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		writer.Comma = opts.Comma
+	}
+	if !opts.OmitHeader {
+		if err := writer.Write(columns); err != nil {
+			return err
+		}
+	}
+	for _, t := range *timerlist {
+		record := make([]string, len(columns))
+		for i, c := range columns {
+			record[i], _ = csvField(t, c, separator)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvField returns the value of the named CSV column for t, and false if there is no such column.
+func csvField(t Timer, column, separator string) (string, bool) {
+	var v string
+	switch column {
+	case "Id":
+		v = strconv.Itoa(t.Id)
+	case "StartDate":
+		v = t.formatDate(t.StartDate)
+	case "FinishDate":
+		if !t.FinishDate.IsZero() {
+			v = t.formatDate(t.FinishDate)
+		}
+	case "Duration":
+		v = strconv.FormatFloat(t.Duration().Hours(), 'f', 2, 64)
+	case "Finished":
+		v = strconv.FormatBool(t.Finished)
+	case "Notes":
+		v = t.Notes
+	case "Projects":
+		v = strings.Join(t.Projects, separator)
+	case "Contexts":
+		v = strings.Join(t.Contexts, separator)
+	default:
+		return "", false
+	}
+	return v, true
+}
+
+// LoadCSV loads a TimerList from CSV as written by WriteCSV.
+// Columns are matched by the header row, so they may be in any order. The StartDate column is required,
+// the Id and Duration columns are ignored and timers are assigned sequential ids.
+// The loaded timers have no Priority or additional tags, as WriteCSV doesn't write them.
+// Note: On success this will overwrite the current TimerList with whatever is read from r. On error the
+// TimerList is left unchanged.
+func (timerlist *TimerList) LoadCSV(r io.Reader) error {
+	return timerlist.LoadCSVWithOptions(r, CSVOptions{})
+}
+
+// LoadCSVWithOptions loads a TimerList from CSV as written by WriteCSVWithOptions with the same opts.
+// Without a header row, the columns are taken from opts.Columns, see LoadCSV.
+func (timerlist *TimerList) LoadCSVWithOptions(r io.Reader, opts CSVOptions) error {
+	reader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		reader.Comma = opts.Comma
+	}
+	separator := opts.ListSeparator
+	if separator == "" {
+		separator = csvListSeparator
+	}
+	header := opts.Columns
+	if len(header) == 0 {
+		header = csvHeader
+	}
+	if !opts.OmitHeader {
+		var err error
+		if header, err = reader.Read(); err != nil {
+			if err == io.EOF {
+				return errors.New("missing CSV header")
+			}
+			return err
+		}
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	if _, ok := columns["StartDate"]; !ok {
+		return errors.New("missing required CSV column: StartDate")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	loaded := TimerList{}
+	timerId := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		line, _ := reader.FieldPos(0)
+		timer := Timer{Id: timerId, AdditionalTags: make(map[string]string)}
+		if timer.StartDate, err = parseDate("", field(record, "StartDate")); err != nil {
+			return fmt.Errorf("line %d: Unable to parse StartDate: %w", line, err)
+		}
+		if v := field(record, "FinishDate"); v != "" {
+			if timer.FinishDate, err = parseDate("", v); err != nil {
+				return fmt.Errorf("line %d: Unable to parse FinishDate: %w", line, err)
+			}
+		}
+		if v := field(record, "Finished"); v != "" {
+			if timer.Finished, err = strconv.ParseBool(v); err != nil {
+				return fmt.Errorf("line %d: Unable to parse Finished: %w", line, err)
+			}
+		}
+		timer.Finished = timer.Finished || !timer.FinishDate.IsZero()
+		timer.Notes = field(record, "Notes")
+		timer.Projects = splitCSVList(field(record, "Projects"), separator)
+		timer.Contexts = splitCSVList(field(record, "Contexts"), separator)
+		loaded = append(loaded, timer)
+		timerId++
+	}
+	*timerlist = loaded
+	return nil
+}
+
+// splitCSVList splits a Projects or Contexts field joined with separator, dropping empty values.
+func splitCSVList(field, separator string) []string {
+	var values []string
+	for _, v := range strings.Split(field, separator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,74 @@
+package timertxt
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// icsDateLayout is the iCalendar UTC date-time format.
+const icsDateLayout = "20060102T150405Z"
+
+// icsEscaper escapes text values according to RFC 5545.
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+// WriteICS writes the TimerList to w as an iCalendar (.ics) file.
+// Every finished timer becomes an event, using the Notes as summary and the Contexts and Projects as categories.
+// Active timers are skipped.
+func (timerlist *TimerList) WriteICS(w io.Writer) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//br0xen//go-timertxt//EN",
+	}
+	stamp := time.Now().UTC().Format(icsDateLayout)
+	for _, t := range *timerlist {
+		if t.FinishDate.IsZero() {
+			continue
+		}
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:%d-%d@go-timertxt", t.Id, t.StartDate.Unix()),
+			"DTSTAMP:"+stamp,
+			"DTSTART:"+t.StartDate.UTC().Format(icsDateLayout),
+			"DTEND:"+t.FinishDate.UTC().Format(icsDateLayout),
+			"SUMMARY:"+icsEscaper.Replace(t.Notes),
+		)
+		var categories []string
+		for _, c := range t.Contexts {
+			categories = append(categories, icsEscaper.Replace("@"+c))
+		}
+		for _, p := range t.Projects {
+			categories = append(categories, icsEscaper.Replace("+"+p))
+		}
+		if len(categories) > 0 {
+			lines = append(lines, "CATEGORIES:"+strings.Join(categories, ","))
+		}
+		lines = append(lines, "END:VEVENT")
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICSLine(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// foldICSLine splits lines longer than 75 octets, continuing them on the next line with a leading space.
+func foldICSLine(line string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range line {
+		size := len(string(r))
+		if n+size > 75 {
+			b.WriteString("\r\n ")
+			n = 1
+		}
+		b.WriteRune(r)
+		n += size
+	}
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package timertxt
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// jsonTimer is the JSON representation of a Timer.
+type jsonTimer struct {
+	Id                  int                 `json:"id,omitempty"`
+	StartDate           string              `json:"start_date"`
+	FinishDate          string              `json:"finish_date,omitempty"`
+	Finished            bool                `json:"finished"`
+	Priority            string              `json:"priority,omitempty"`
+	Notes               string              `json:"notes"`
+	Projects            []string            `json:"projects"`
+	Contexts            []string            `json:"contexts"`
+	AdditionalTags      map[string]string   `json:"additional_tags"`
+	AdditionalTagsMulti map[string][]string `json:"additional_tags_multi,omitempty"`
+	LastActive          string              `json:"last_active,omitempty"`
+}
+
+// MarshalJSON returns the timer as a JSON object.
+// Dates are formatted as RFC3339 strings, and the finish date is omitted if the timer has none.
+//
+// Note: a struct that embeds a Timer gets this method promoted, so encoding/json writes only the Timer and drops
+// the other fields of the struct. Use a named field for the Timer instead.
+func (timer Timer) MarshalJSON() ([]byte, error) {
+	jt := jsonTimer{
+		Id:             timer.Id,
+		StartDate:      timer.StartDate.Format(time.RFC3339),
+		Finished:       timer.Finished,
+		Priority:       timer.Priority,
+		Notes:          timer.Notes,
+		Projects:       append([]string{}, timer.Projects...),
+		Contexts:       append([]string{}, timer.Contexts...),
+		AdditionalTags: make(map[string]string),
+	}
+	if !timer.FinishDate.IsZero() {
+		jt.FinishDate = timer.FinishDate.Format(time.RFC3339)
+	}
+	if !timer.LastActive.IsZero() {
+		jt.LastActive = timer.LastActive.Format(time.RFC3339)
+	}
+	for k, v := range timer.AdditionalTags {
+		jt.AdditionalTags[k] = v
+	}
+	for k, v := range timer.AdditionalTagsMulti {
+		if jt.AdditionalTagsMulti == nil {
+			jt.AdditionalTagsMulti = make(map[string][]string)
+		}
+		jt.AdditionalTagsMulti[k] = append([]string{}, v...)
+	}
+	return json.Marshal(jt)
+}
+
+// UnmarshalJSON sets the timer from a JSON object as produced by MarshalJSON.
+// Returns an error if the timer is marked finished but has no finish date.
+func (timer *Timer) UnmarshalJSON(data []byte) error {
+	var jt jsonTimer
+	if err := json.Unmarshal(data, &jt); err != nil {
+		return err
+	}
+	t := Timer{
+		Id:                  jt.Id,
+		Finished:            jt.Finished,
+		Priority:            jt.Priority,
+		Notes:               jt.Notes,
+		Projects:            jt.Projects,
+		Contexts:            jt.Contexts,
+		AdditionalTags:      jt.AdditionalTags,
+		AdditionalTagsMulti: jt.AdditionalTagsMulti,
+	}
+	if t.AdditionalTags == nil {
+		t.AdditionalTags = make(map[string]string)
+	}
+	var err error
+	if t.StartDate, err = time.Parse(time.RFC3339, jt.StartDate); err != nil {
+		return errors.New("Unable to parse start_date: " + err.Error())
+	}
+	if jt.FinishDate != "" {
+		if t.FinishDate, err = time.Parse(time.RFC3339, jt.FinishDate); err != nil {
+			return errors.New("Unable to parse finish_date: " + err.Error())
+		}
+	} else if t.Finished {
+		return errors.New("Timer marked finished, but has no finish_date")
+	}
+	if jt.LastActive != "" {
+		if t.LastActive, err = time.Parse(time.RFC3339, jt.LastActive); err != nil {
+			return errors.New("Unable to parse last_active: " + err.Error())
+		}
+	}
+	*timer = t
+	return nil
+}
+
+// MarshalJSON returns the TimerList as a JSON array of timers.
+// An empty TimerList is marshaled as '[]'.
+func (timerlist TimerList) MarshalJSON() ([]byte, error) {
+	if timerlist == nil {
+		timerlist = TimerList{}
+	}
+	return json.Marshal([]Timer(timerlist))
+}
+
+// LoadFromJSON loads a TimerList from a JSON array of timers, as produced by MarshalJSON.
+// Note: This will clear the current TimerList and overwrite it's contents, assigning sequential ids.
+func (timerlist *TimerList) LoadFromJSON(r io.Reader) error {
+	var timers []Timer
+	if err := json.NewDecoder(r).Decode(&timers); err != nil {
+		return err
+	}
+	*timerlist = []Timer{} // Empty timerlist
+	for i, t := range timers {
+		t.Id = i + 1
+		*timerlist = append(*timerlist, t)
+	}
+	return nil
+}
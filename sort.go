@@ -3,6 +3,7 @@ package timertxt
 import (
 	"errors"
 	"sort"
+	"strings"
 	"time"
 )
 
@@ -13,10 +14,18 @@ const (
 	SORT_START_DATE_DESC
 	SORT_FINISH_DATE_ASC
 	SORT_FINISH_DATE_DESC
+	SORT_PRIORITY_ASC
+	SORT_PRIORITY_DESC
+	SORT_DURATION_ASC
+	SORT_DURATION_DESC
+	SORT_NOTES_ASC
+	SORT_NOTES_DESC
 )
 
 // Sort allows a TimerList to be sorted by certain predefined fields.
 // See constants SORT_* for fields and sort order.
+// Timers that are equal on the chosen field are ordered by StartDate and then by Id, both ascending,
+// so the resulting order is deterministic.
 func (timerlist *TimerList) Sort(sortFlag int) error {
 	switch sortFlag {
 	case SORT_UNFINISHED_START:
@@ -25,12 +34,35 @@ func (timerlist *TimerList) Sort(sortFlag int) error {
 		timerlist.sortByStartDate(sortFlag)
 	case SORT_FINISH_DATE_ASC, SORT_FINISH_DATE_DESC:
 		timerlist.sortByFinishDate(sortFlag)
+	case SORT_PRIORITY_ASC, SORT_PRIORITY_DESC:
+		timerlist.sortByPriority(sortFlag)
+	case SORT_DURATION_ASC, SORT_DURATION_DESC:
+		timerlist.sortByDuration(sortFlag)
+	case SORT_NOTES_ASC, SORT_NOTES_DESC:
+		timerlist.sortByNotes(sortFlag)
 	default:
 		return errors.New("Unrecognized sort option")
 	}
 	return nil
 }
 
+// SortBy sorts the TimerList with a custom ordering, where less reports whether a sorts before b.
+// Ties are broken the same way as Sort(), by StartDate and then by Id.
+func (timerlist *TimerList) SortBy(less func(a, b Timer) bool) {
+	timerlist.sortBy(func(t1, t2 *Timer) bool {
+		return less(*t1, *t2)
+	})
+}
+
+// Reverse reverses the order of the TimerList in place, e.g. to flip the result of an ascending Sort().
+// Timers keep their Ids.
+func (timerlist *TimerList) Reverse() {
+	l := *timerlist
+	for i, j := 0, len(l)-1; i < j; i, j = i+1, j-1 {
+		l[i], l[j] = l[j], l[i]
+	}
+}
+
 type timerlistSort struct {
 	timerlists TimerList
 	by         func(t1, t2 *Timer) bool
@@ -51,9 +83,20 @@ func (ts *timerlistSort) Less(l, r int) bool {
 func (timerlist *TimerList) sortBy(by func(t1, t2 *Timer) bool) *TimerList {
 	ts := &timerlistSort{
 		timerlists: *timerlist,
-		by:         by,
+		by: func(t1, t2 *Timer) bool {
+			if by(t1, t2) {
+				return true
+			} else if by(t2, t1) {
+				return false
+			}
+			// Tie, fall back to StartDate and then Id
+			if !t1.StartDate.Equal(t2.StartDate) {
+				return sortByDate(true, t1.StartDate, t2.StartDate)
+			}
+			return t1.Id < t2.Id
+		},
 	}
-	sort.Sort(ts)
+	sort.Stable(ts)
 	return timerlist
 }
 
@@ -68,7 +111,7 @@ func sortByDate(asc bool, date1, date2 time.Time) bool {
 	if !date1.IsZero() && !date2.IsZero() {
 		return date1.After(date2)
 	}
-	return date2.IsZero()
+	return !date1.IsZero() && date2.IsZero()
 }
 
 func (timerlist *TimerList) sortByStartDate(order int) *TimerList {
@@ -96,3 +139,47 @@ func (timerlist *TimerList) sortByUnfinishedThenStart() *TimerList {
 	})
 	return timerlist
 }
+
+// sortByPriority sorts timers with a priority in alphabetical order, (A) first.
+// Timers without a priority are placed last when ascending and first when descending.
+func (timerlist *TimerList) sortByPriority(order int) *TimerList {
+	timerlist.sortBy(func(t1, t2 *Timer) bool {
+		if order == SORT_PRIORITY_ASC { // ASC
+			if t1.Priority != "" && t2.Priority != "" {
+				return t1.Priority < t2.Priority
+			}
+			return t1.Priority != "" && t2.Priority == ""
+		}
+		// DESC
+		if t1.Priority != "" && t2.Priority != "" {
+			return t1.Priority > t2.Priority
+		}
+		return t1.Priority == "" && t2.Priority != ""
+	})
+	return timerlist
+}
+
+// sortByDuration sorts timers by their Duration, with unfinished timers running up to the time of sorting.
+func (timerlist *TimerList) sortByDuration(order int) *TimerList {
+	now := time.Now()
+	timerlist.sortBy(func(t1, t2 *Timer) bool {
+		if order == SORT_DURATION_ASC {
+			return t1.DurationAsOf(now) < t2.DurationAsOf(now)
+		}
+		return t1.DurationAsOf(now) > t2.DurationAsOf(now)
+	})
+	return timerlist
+}
+
+// sortByNotes sorts timers alphabetically by their Notes, ignoring case.
+// Timers with the same Notes are ordered by StartDate.
+func (timerlist *TimerList) sortByNotes(order int) *TimerList {
+	timerlist.sortBy(func(t1, t2 *Timer) bool {
+		n1, n2 := strings.ToLower(t1.Notes), strings.ToLower(t2.Notes)
+		if order == SORT_NOTES_ASC {
+			return n1 < n2
+		}
+		return n1 > n2
+	})
+	return timerlist
+}
@@ -0,0 +1,120 @@
+package timertxt
+
+import (
+	"io"
+	"sync"
+)
+
+// SyncTimerList wraps a TimerList so it can be used from multiple goroutines.
+// Timers are copied in and out of the list, so callers never share data with it.
+type SyncTimerList struct {
+	mu   sync.RWMutex
+	list TimerList
+}
+
+// NewSyncTimerList creates a new empty SyncTimerList.
+func NewSyncTimerList() *SyncTimerList {
+	return &SyncTimerList{list: TimerList{}}
+}
+
+// TimerList returns a copy of the wrapped TimerList.
+func (s *SyncTimerList) TimerList() *TimerList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.Clone()
+}
+
+// String returns a complete list of timers in timer.txt format.
+func (s *SyncTimerList) String() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.String()
+}
+
+// AddTimer prepends a copy of the Timer to the list, see *TimerList.AddTimer().
+func (s *SyncTimerList) AddTimer(timer *Timer) {
+	t := timer.Clone()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list.AddTimer(&t)
+	timer.Id = t.Id
+}
+
+// GetTimer returns a copy of the Timer with the given timer 'id'.
+// Returns an error if Timer could not be found.
+func (s *SyncTimerList) GetTimer(id int) (Timer, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, err := s.list.GetTimer(id)
+	if err != nil {
+		return Timer{}, err
+	}
+	return t.Clone(), nil
+}
+
+// UpdateTimer replaces the Timer with the given timer 'id', see *TimerList.UpdateTimer().
+func (s *SyncTimerList) UpdateTimer(id int, timer Timer) error {
+	t := timer.Clone()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.UpdateTimer(id, t)
+}
+
+// RemoveTimerById removes any Timer with given Timer 'id', see *TimerList.RemoveTimerById().
+func (s *SyncTimerList) RemoveTimerById(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.RemoveTimerById(id)
+}
+
+// RemoveTimer removes any Timer that is Equal to the given Timer, see *TimerList.RemoveTimer().
+func (s *SyncTimerList) RemoveTimer(timer Timer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.list.RemoveTimer(timer)
+}
+
+// Filter returns a new TimerList with the timers matching the predicate, see *TimerList.Filter().
+func (s *SyncTimerList) Filter(predicate func(Timer) bool) *TimerList {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.Filter(predicate)
+}
+
+// LoadFrom replaces the list with the timers read from r, see *TimerList.LoadFrom().
+func (s *SyncTimerList) LoadFrom(r io.Reader) error {
+	var list TimerList
+	if err := list.LoadFrom(r); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = list
+	return nil
+}
+
+// LoadFromFilename replaces the list with the timers in the specified file, see *TimerList.LoadFromFilename().
+func (s *SyncTimerList) LoadFromFilename(filename string) error {
+	var list TimerList
+	if err := list.LoadFromFilename(filename); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.list = list
+	return nil
+}
+
+// WriteTo writes the list to an io.Writer in timer.txt format, see *TimerList.WriteTo().
+func (s *SyncTimerList) WriteTo(w io.Writer) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.WriteTo(w)
+}
+
+// WriteToFilename writes the list to the specified file, see *TimerList.WriteToFilename().
+func (s *SyncTimerList) WriteToFilename(filename string) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.list.WriteToFilename(filename)
+}
@@ -7,27 +7,57 @@ import (
 	"sort"
 	"strings"
 	"time"
+	"unicode"
 )
 
 var (
 	// DateLayout is used for formatting time.Time into timer.txt date format and vice-versa.
 	DateLayout = time.RFC3339
 
-	addonTagRx = regexp.MustCompile(`(^|\s+)([\w-]+):(\S+)`) // Match additional tags date: '... due:2012-12-12 ...'
-	contextRx  = regexp.MustCompile(`(^|\s+)@(\S+)`)         // Match contexts: '@Context ...' or '... @Context ...'
-	projectRx  = regexp.MustCompile(`(^|\s+)\+(\S+)`)        // Match projects: '+Project...' or '... +Project ...')
+	// inputDateLayouts are additional layouts accepted when parsing dates, tried in order after DateLayout.
+	inputDateLayouts = []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05Z0700", // Offset without a colon: '2019-02-15T11:43:00-0600'
+	}
+
+	priorityRx     = regexp.MustCompile(`^\(([A-Z])\)$`)                  // Match priority: '(A)'
+	addonTagRx     = regexp.MustCompile(`(^|\s+)([A-Za-z_][\w-]*):(\S+)`) // Match additional tags date: '... due:2012-12-12 ...', but not '3:1'
+	contextRx      = regexp.MustCompile(`(^|\s+)@(\S+)`)                  // Match contexts: '@Context ...' or '... @Context ...'
+	projectRx      = regexp.MustCompile(`(^|\s+)\+(\S+)`)                 // Match projects: '+Project...' or '... +Project ...')
+	malformedTagRx = regexp.MustCompile(`^([A-Za-z_][\w-]*:|:\S+)$`)      // Match tags missing a key or value in strict mode: 'due:' or ':today'
 )
 
+// durationTagKey is the additional tag key written when Timer.EmitDurationTag is set.
+const durationTagKey = "dur"
+
+// lastActiveTagKey is the additional tag key that keeps Timer.LastActive of running timers in timer.txt.
+const lastActiveTagKey = "lastactive"
+
+// idTagKey is the additional tag key read as the timer id when LoadOptions.IdFromTag is set.
+const idTagKey = "id"
+
+// Timer is a single timer.txt entry.
+// It is encoded to JSON by MarshalJSON, see there before embedding a Timer in another struct.
 type Timer struct {
-	Id             int    // Internal timer id
-	Original       string // Original raw timer text
-	StartDate      time.Time
-	FinishDate     time.Time
-	Finished       bool
-	Notes          string // Notes part of timer text
-	Projects       []string
-	Contexts       []string
-	AdditionalTags map[string]string // Addon tags will be available here
+	Id                  int    // Internal timer id
+	Original            string // Original raw timer text
+	StartDate           time.Time
+	FinishDate          time.Time
+	Finished            bool   // Kept in sync with FinishDate, which is the source of truth
+	Priority            string // Priority letter, without the parentheses
+	Notes               string // Notes part of timer text
+	Projects            []string
+	Contexts            []string
+	AdditionalTags      map[string]string   // Addon tags will be available here
+	AdditionalTagsMulti map[string][]string // All values of repeated tag keys, AdditionalTags holds the last one
+	Layout              string              // Date layout used by String(), DateLayout is used when empty
+	Precision           time.Duration       // If set, String() truncates dates to a multiple of it, e.g. time.Second
+	EmitDurationTag     bool                // Add a computed 'dur:HH:MM' tag to String() for finished timers
+	Indent              string              // Leading whitespace written by String(), see LoadOptions.KeepIndent
+	Comments            []string            // '#' comment lines above the timer, see LoadOptions.KeepComments
+	TrailingComments    []string            // '#' comment lines at the end of the file, always written after the last timer
+	Newline             string              // Line ending read after the timer, written back by WriteTo
+	LastActive          time.Time           // Last heartbeat of a running timer, see Touch()
 }
 
 // String returns a complete timer string in timer.txt format.
@@ -35,46 +65,187 @@ type Timer struct {
 // Contexts, Projects, and additional tags are alphabetically sorted,
 // and appended at the end in the following order:
 // Contexts, Projects, Tags
+// The sorting only applies to the output, the order of the timer's own slices is left untouched.
+//
+// A priority is written after the finished marker and before the dates: "x (A) 2019-02-15T06:00:00-06:00 ..."
+//
+// Parts are separated by a single space, and nothing follows the last part. An unfinished timer without notes is
+// written as just its StartDate, with no trailing space.
+//
+// Running timers with a LastActive get a 'lastactive:' tag with that date, which ParseTimer reads back.
+//
+// If EmitDurationTag is set, finished timers get a computed 'dur:HH:MM' tag, unless they already have a 'dur' tag.
+// ParseTimer drops a 'dur' tag that matches the duration of a finished timer and sets EmitDurationTag instead,
+// so it doesn't accumulate. Any other 'dur' tag is kept like any additional tag.
 //
 // For example:
-// "2019-02-15T11:43:00-0600 Working on Go Library @home @personal +timertxt customTag1:Important! due:Today"
-// "x 2019-02-15T06:00:00-0600 2019-02-15T10:00:00-0600 Creating Go Library Repo @home @personal +timertxt customTag1:Important! due:Today"
+// "2019-02-15T11:43:00-06:00 Working on Go Library @home @personal +timertxt customTag1:Important! due:Today"
+// "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Creating Go Library Repo @home @personal +timertxt customTag1:Important! due:Today"
 func (timer Timer) String() string {
-	var text string
+	var b strings.Builder
+	b.WriteString(timer.Indent)
 	if timer.Finished {
-		text += "x "
+		b.WriteString("x ")
+	}
+	if timer.Priority != "" {
+		b.WriteByte('(')
+		b.WriteString(timer.Priority)
+		b.WriteString(") ")
 	}
-	text += fmt.Sprintf("%s ", timer.StartDate.Format(DateLayout))
+	b.WriteString(timer.formatDate(timer.StartDate))
 	if !timer.FinishDate.IsZero() {
-		text += fmt.Sprintf("%s", timer.FinishDate.Format(DateLayout))
+		b.WriteByte(' ')
+		b.WriteString(timer.formatDate(timer.FinishDate))
 	}
 	if len(timer.Notes) > 0 {
-		text += " " + timer.Notes
+		b.WriteByte(' ')
+		b.WriteString(timer.Notes)
 	}
 	if len(timer.Contexts) > 0 {
-		sort.Strings(timer.Contexts)
-		for _, context := range timer.Contexts {
-			text += fmt.Sprintf(" @%s", context)
+		for _, context := range sortedStrings(timer.Contexts) {
+			b.WriteString(" @")
+			b.WriteString(context)
 		}
 	}
 	if len(timer.Projects) > 0 {
-		sort.Strings(timer.Projects)
-		for _, project := range timer.Projects {
-			text += fmt.Sprintf(" +%s", project)
+		for _, project := range sortedStrings(timer.Projects) {
+			b.WriteString(" +")
+			b.WriteString(project)
 		}
 	}
-	if len(timer.AdditionalTags) > 0 {
+	tags := timer.AdditionalTags
+	computed := make(map[string]string)
+	if timer.EmitDurationTag && !timer.FinishDate.IsZero() {
+		computed[durationTagKey] = durationTagValue(timer.Duration())
+	}
+	if !timer.LastActive.IsZero() && timer.FinishDate.IsZero() {
+		computed[lastActiveTagKey] = timer.formatDate(timer.LastActive)
+	}
+	if len(computed) > 0 {
+		tags = make(map[string]string, len(timer.AdditionalTags)+len(computed))
+		for k, v := range timer.AdditionalTags {
+			tags[k] = v
+		}
+		for k, v := range computed {
+			// Tags of the timer itself are never overwritten
+			if _, ok := tags[k]; !ok {
+				tags[k] = v
+			}
+		}
+	}
+	if len(tags) > 0 {
 		// Sort map alphabetically by keys
-		keys := make([]string, 0, len(timer.AdditionalTags))
-		for key := range timer.AdditionalTags {
+		keys := make([]string, 0, len(tags))
+		for key := range tags {
 			keys = append(keys, key)
 		}
 		sort.Strings(keys)
 		for _, key := range keys {
-			text += fmt.Sprintf(" %s:%s", key, timer.AdditionalTags[key])
+			values := []string{tags[key]}
+			if multi := timer.AdditionalTagsMulti[key]; len(multi) > 0 && multi[len(multi)-1] == tags[key] {
+				values = multi
+			}
+			for _, value := range values {
+				b.WriteByte(' ')
+				b.WriteString(key)
+				b.WriteByte(':')
+				b.WriteString(value)
+			}
+		}
+	}
+	return b.String()
+}
+
+// Clone returns a deep copy of the timer, which shares no slices or maps with the original.
+func (timer Timer) Clone() Timer {
+	clone := timer
+	if timer.Projects != nil {
+		clone.Projects = append([]string{}, timer.Projects...)
+	}
+	if timer.Contexts != nil {
+		clone.Contexts = append([]string{}, timer.Contexts...)
+	}
+	if timer.Comments != nil {
+		clone.Comments = append([]string{}, timer.Comments...)
+	}
+	if timer.TrailingComments != nil {
+		clone.TrailingComments = append([]string{}, timer.TrailingComments...)
+	}
+	if timer.AdditionalTags != nil {
+		clone.AdditionalTags = make(map[string]string, len(timer.AdditionalTags))
+		for k, v := range timer.AdditionalTags {
+			clone.AdditionalTags[k] = v
+		}
+	}
+	if timer.AdditionalTagsMulti != nil {
+		clone.AdditionalTagsMulti = make(map[string][]string, len(timer.AdditionalTagsMulti))
+		for k, v := range timer.AdditionalTagsMulti {
+			clone.AdditionalTagsMulti[k] = append([]string{}, v...)
+		}
+	}
+	return clone
+}
+
+// InLocation returns a copy of the timer with its dates converted to the given location.
+// The instants don't change, only the timezone they are written in.
+func (timer Timer) InLocation(loc *time.Location) Timer {
+	t := timer.Clone()
+	t.StartDate = t.StartDate.In(loc)
+	if !t.FinishDate.IsZero() {
+		t.FinishDate = t.FinishDate.In(loc)
+	}
+	return t
+}
+
+// Equal reports whether both timers hold the same timer data.
+// The Id and Original text are ignored, as is the order of Contexts, Projects and additional tags.
+func (timer Timer) Equal(other Timer) bool {
+	if !timer.StartDate.Equal(other.StartDate) || !timer.FinishDate.Equal(other.FinishDate) {
+		return false
+	}
+	if timer.Finished != other.Finished || timer.Priority != other.Priority || timer.Notes != other.Notes {
+		return false
+	}
+	if !equalStrings(timer.Contexts, other.Contexts) || !equalStrings(timer.Projects, other.Projects) {
+		return false
+	}
+	if len(timer.AdditionalTags) != len(other.AdditionalTags) {
+		return false
+	}
+	for k, v := range timer.AdditionalTags {
+		if ov, ok := other.AdditionalTags[k]; !ok || ov != v {
+			return false
+		}
+		if !equalStrings(timer.TagValues(k), other.TagValues(k)) {
+			return false
 		}
 	}
-	return text
+	return true
+}
+
+// sortedStrings returns the strings in alphabetical order without modifying the slice.
+// An already sorted slice is returned as is, only unsorted slices are copied and sorted.
+func sortedStrings(strs []string) []string {
+	if sort.StringsAreSorted(strs) {
+		return strs
+	}
+	sorted := append([]string{}, strs...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+// equalStrings reports whether both slices hold the same strings, regardless of order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sa, sb := sortedStrings(a), sortedStrings(b)
+	for i := range sa {
+		if sa[i] != sb[i] {
+			return false
+		}
+	}
+	return true
 }
 
 // NewTimer creates a new empty Timer with default values. (StartDate is set to Now())
@@ -85,47 +256,173 @@ func NewTimer() *Timer {
 	return &timer
 }
 
+// WithNotes sets the timer's Notes and returns the timer, for chaining.
+func (timer *Timer) WithNotes(notes string) *Timer {
+	timer.Notes = notes
+	return timer
+}
+
+// WithContext adds a context to the timer and returns the timer, for chaining. See AddContext.
+func (timer *Timer) WithContext(context string) *Timer {
+	timer.AddContext(context)
+	return timer
+}
+
+// WithProject adds a project to the timer and returns the timer, for chaining. See AddProject.
+func (timer *Timer) WithProject(project string) *Timer {
+	timer.AddProject(project)
+	return timer
+}
+
+// WithTag sets an additional tag and returns the timer, for chaining.
+// Invalid tags are ignored, see SetTag.
+func (timer *Timer) WithTag(key, value string) *Timer {
+	timer.SetTag(key, value)
+	return timer
+}
+
+// WithStart sets the timer's StartDate and returns the timer, for chaining.
+func (timer *Timer) WithStart(t time.Time) *Timer {
+	timer.StartDate = t
+	return timer
+}
+
+// ParseError is returned when timer text can't be parsed.
+// Field names the part that failed: "Text", "StartDate", "FinishDate", "AdditionalTags" or "Notes".
+type ParseError struct {
+	Text  string // The timer text being parsed
+	Field string // The field that failed to parse
+	Err   error  // The cause of the failure
+}
+
+func (e *ParseError) Error() string {
+	return "Unable to parse " + e.Field + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
 // ParseTimer parses the input text string into a Timer struct
+// Returns a *ParseError if the text can't be parsed.
 func ParseTimer(text string) (*Timer, error) {
+	return ParseTimerWithLayout(text, "")
+}
+
+// ParseTimerWithLayout parses the input text string into a Timer struct, trying the given date layout
+// before the other accepted layouts. The layout is kept in Timer.Layout and used by String().
+// An empty layout uses DateLayout.
+func ParseTimerWithLayout(text, layout string) (*Timer, error) {
+	return parseTimer(text, LoadOptions{DateLayout: layout})
+}
+
+// ParseTimerStrict parses the input text string into a Timer struct, like ParseTimer, but returns an error
+// for repeated additional tag keys and for malformed tokens: a bare '@' or '+', or a tag missing its key or value.
+func ParseTimerStrict(text string) (*Timer, error) {
+	return parseTimer(text, LoadOptions{Strict: true})
+}
+
+// parseTimer parses the input text string into a Timer struct, as configured by opts.
+func parseTimer(text string, opts LoadOptions) (*Timer, error) {
 	var err error
-	timer := Timer{}
+	layout := opts.DateLayout
+	timer := Timer{Layout: layout}
+	timer.AdditionalTags = make(map[string]string)
 	timer.Original = strings.Trim(text, "\t\n\r ")
 	originalParts := strings.Fields(timer.Original)
+	if len(originalParts) == 0 {
+		return nil, &ParseError{Text: text, Field: "Text", Err: errors.New("empty timer text")}
+	}
 
 	// Check for finished
 	if originalParts[0] == "x" {
 		timer.Finished = true
 		originalParts = originalParts[1:]
+	} else if strings.HasPrefix(originalParts[0], "x") {
+		// Some editors leave out the space: 'x2019-02-15T06:00:00-06:00 ...'
+		if _, err := parseDate(layout, originalParts[0][1:]); err == nil {
+			timer.Finished = true
+			originalParts[0] = originalParts[0][1:]
+		}
+	}
+	// Check for priority
+	if len(originalParts) > 0 {
+		if m := priorityRx.FindStringSubmatch(originalParts[0]); m != nil {
+			timer.Priority = m[1]
+			originalParts = originalParts[1:]
+		}
+	}
+	if len(originalParts) == 0 {
+		return nil, &ParseError{Text: text, Field: "StartDate", Err: errors.New("missing date")}
 	}
-	if timer.StartDate, err = time.Parse(DateLayout, originalParts[0]); err != nil {
-		return nil, errors.New("Unable to parse StartDate: " + err.Error())
+	if timer.StartDate, err = parseDate(layout, originalParts[0]); err != nil {
+		return nil, &ParseError{Text: text, Field: "StartDate", Err: err}
 	}
 	originalParts = originalParts[1:]
 	if timer.Finished {
 		// If it's finished, there _must_ be a finished date
-		if timer.FinishDate, err = time.Parse(DateLayout, originalParts[0]); err != nil {
-			return nil, errors.New("Timer marked finished, but failed to parse FinishDate: " + err.Error())
+		if len(originalParts) == 0 {
+			return nil, &ParseError{Text: text, Field: "FinishDate", Err: errors.New("Timer marked finished, but missing date")}
+		}
+		if timer.FinishDate, err = parseDate(layout, originalParts[0]); err != nil {
+			return nil, &ParseError{Text: text, Field: "FinishDate", Err: fmt.Errorf("Timer marked finished: %w", err)}
 		}
 		originalParts = originalParts[1:]
+	} else if len(originalParts) > 0 {
+		// A finish date without the 'x' still marks the timer finished
+		if finishDate, err := parseDate(layout, originalParts[0]); err == nil {
+			timer.FinishDate = finishDate
+			timer.Finished = true
+			originalParts = originalParts[1:]
+		}
+	}
+	rest := strings.Join(originalParts, " ")
+	// Contexts
+	for _, m := range contextRx.FindAllStringSubmatch(rest, -1) {
+		timer.Contexts = append(timer.Contexts, m[2])
 	}
-	var notes []string
-	for _, v := range originalParts {
-		if strings.HasPrefix(v, "@") {
-			v = strings.TrimPrefix(v, "@")
-			// Contexts
-			timer.Contexts = append(timer.Contexts, v)
-		} else if strings.HasPrefix(v, "+") {
-			// Projects
-			v = strings.TrimPrefix(v, "+")
-			timer.Projects = append(timer.Projects, v)
-		} else if strings.Contains(v, ":") {
-			// Additional tags
-			tagPts := strings.Split(v, ":")
-			if tagPts[0] != "" && tagPts[1] != "" {
-				timer.AdditionalTags[tagPts[0]] = tagPts[1]
+	rest = contextRx.ReplaceAllString(rest, "")
+	// Projects
+	for _, m := range projectRx.FindAllStringSubmatch(rest, -1) {
+		timer.Projects = append(timer.Projects, m[2])
+	}
+	rest = projectRx.ReplaceAllString(rest, "")
+	// Additional tags
+	tagValues := make(map[string][]string)
+	for _, m := range addonTagRx.FindAllStringSubmatch(rest, -1) {
+		if m[2] == durationTagKey && timer.Finished && m[3] == durationTagValue(timer.FinishDate.Sub(timer.StartDate)) {
+			// A computed duration tag, which is recomputed by String() rather than kept
+			timer.EmitDurationTag = true
+			continue
+		}
+		if m[2] == lastActiveTagKey && !timer.Finished {
+			if lastActive, err := parseDate(layout, m[3]); err == nil {
+				timer.LastActive = lastActive
+				continue
+			}
+		}
+		if _, ok := timer.AdditionalTags[m[2]]; ok && opts.Strict {
+			return nil, &ParseError{Text: text, Field: "AdditionalTags", Err: fmt.Errorf("Duplicate additional tag %q", m[2])}
+		}
+		timer.AdditionalTags[m[2]] = m[3]
+		tagValues[m[2]] = append(tagValues[m[2]], m[3])
+	}
+	for k, v := range tagValues {
+		if len(v) > 1 {
+			if timer.AdditionalTagsMulti == nil {
+				timer.AdditionalTagsMulti = make(map[string][]string)
+			}
+			timer.AdditionalTagsMulti[k] = v
+		}
+	}
+	rest = addonTagRx.ReplaceAllString(rest, "")
+	// Everything else is notes
+	notes := strings.Fields(rest)
+	if opts.Strict {
+		for _, v := range notes {
+			if v == "@" || v == "+" || malformedTagRx.MatchString(v) {
+				return nil, &ParseError{Text: text, Field: "Notes", Err: fmt.Errorf("Malformed token %q", v)}
 			}
-		} else {
-			notes = append(notes, v)
 		}
 	}
 	timer.Notes = strings.Join(notes, " ")
@@ -133,14 +430,89 @@ func ParseTimer(text string) (*Timer, error) {
 	return &timer, nil
 }
 
+// durationTagValue formats d as the value of a computed duration tag: '01:30'.
+func durationTagValue(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// dateLayout returns the layout used to format the timer's dates.
+func (timer Timer) dateLayout() string {
+	if timer.Layout != "" {
+		return timer.Layout
+	}
+	return DateLayout
+}
+
+// formatDate formats a date of the timer with its layout, truncated to its Precision.
+// Parsing still accepts any precision, so only the written dates are affected.
+func (timer Timer) formatDate(t time.Time) string {
+	if timer.Precision > 0 {
+		t = t.Truncate(timer.Precision)
+	}
+	return t.Format(timer.dateLayout())
+}
+
+// parseDate parses a timer.txt date, trying layout first and then the other accepted input layouts.
+// An empty layout uses DateLayout.
+func parseDate(layout, text string) (time.Time, error) {
+	if layout == "" {
+		layout = DateLayout
+	}
+	date, err := time.Parse(layout, text)
+	if err == nil {
+		return date, nil
+	}
+	for _, layout := range inputDateLayouts {
+		if d, e := time.Parse(layout, text); e == nil {
+			return d, nil
+		}
+	}
+	return date, err
+}
+
+// MarshalText returns the timer in timer.txt format, implementing encoding.TextMarshaler.
+func (timer Timer) MarshalText() ([]byte, error) {
+	return []byte(timer.String()), nil
+}
+
+// UnmarshalText parses a timer in timer.txt format into the timer, implementing encoding.TextUnmarshaler.
+func (timer *Timer) UnmarshalText(text []byte) error {
+	t, err := ParseTimer(string(text))
+	if err != nil {
+		return err
+	}
+	*timer = *t
+	return nil
+}
+
+// Raw returns the original text the timer was parsed from.
+// Unlike String(), which canonicalizes the timer, this preserves the input as it was written.
+// Falls back to String() for timers that weren't parsed from text.
+func (timer Timer) Raw() string {
+	if timer.Original == "" {
+		return timer.String()
+	}
+	return timer.Original
+}
+
 // Timer returns a complete timer string in timer.txt format.
 // See *Timer.String() for further information
 func (timer *Timer) Timer() string {
 	return timer.String()
 }
 
+// SetStartDate sets Timer.StartDate to t.
+// Returns an error, leaving the timer unchanged, if the timer is finished and t is after Timer.FinishDate.
+func (timer *Timer) SetStartDate(t time.Time) error {
+	if !timer.FinishDate.IsZero() && t.After(timer.FinishDate) {
+		return errors.New("StartDate can't be after FinishDate")
+	}
+	timer.StartDate = t
+	return nil
+}
+
 // Finish sets Timer.Finished to true if the timer hasn't already been finished.
-// Also sets Timer.FinishDate to time.Now()
+// Also sets Timer.FinishDate to time.Now(), even if the timer starts in the future. Use FinishAt to validate the date.
 func (timer *Timer) Finish() {
 	if !timer.Finished {
 		timer.Finished = true
@@ -148,23 +520,208 @@ func (timer *Timer) Finish() {
 	}
 }
 
+// FinishAt sets Timer.Finished to true and Timer.FinishDate to t.
+// Returns an error, leaving the timer unchanged, if t is before Timer.StartDate.
+func (timer *Timer) FinishAt(t time.Time) error {
+	if t.Before(timer.StartDate) {
+		return errors.New("FinishDate can't be before StartDate")
+	}
+	timer.Finished = true
+	timer.FinishDate = t
+	return nil
+}
+
 // Reopen sets Timer.Finished to 'false' if the timer was finished
 // Also resets Timer.FinishDate
 func (timer *Timer) Reopen() {
-	if timer.Finished {
+	if timer.Finished || !timer.FinishDate.IsZero() {
 		timer.Finished = false
 		timer.FinishDate = time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC) // time.IsZero() value
 	}
 }
 
+// Overlaps returns true if the intervals of both timers intersect. Unfinished timers run up to time.Now().
+// Timers that merely touch, where one finishes exactly when the other starts, don't overlap,
+// but timers with identical intervals always do, even if they have no length.
+func (timer *Timer) Overlaps(other Timer) bool {
+	return timer.overlapsAsOf(other, time.Now())
+}
+
+// overlapsAsOf is Overlaps, with unfinished timers running up to 'now'.
+func (timer *Timer) overlapsAsOf(other Timer, now time.Time) bool {
+	end1, end2 := timer.FinishDate, other.FinishDate
+	if end1.IsZero() {
+		end1 = now
+	}
+	if end2.IsZero() {
+		end2 = now
+	}
+	if timer.StartDate.Equal(other.StartDate) && end1.Equal(end2) {
+		return true
+	}
+	return timer.StartDate.Before(end2) && other.StartDate.Before(end1)
+}
+
+// Split breaks the timer into two at the given time, copying everything else to both parts.
+// The first part runs from StartDate and is finished at 'at', the second starts at 'at' and keeps the
+// FinishDate of the timer. Returns an error if 'at' isn't strictly between StartDate and FinishDate
+// (or time.Now() if the timer is unfinished).
+func (timer Timer) Split(at time.Time) (Timer, Timer, error) {
+	end := timer.FinishDate
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if !at.After(timer.StartDate) || !at.Before(end) {
+		return Timer{}, Timer{}, errors.New("Split time is not within the timer")
+	}
+	first, second := timer.Clone(), timer.Clone()
+	first.Original, second.Original = "", ""
+	first.FinishDate = at
+	first.Finished = true
+	second.StartDate = at
+	return first, second, nil
+}
+
+// HasValidInterval returns false if the timer finishes before it starts.
+func (timer *Timer) HasValidInterval() bool {
+	return timer.FinishDate.IsZero() || !timer.FinishDate.Before(timer.StartDate)
+}
+
+// SwapDatesIfReversed swaps StartDate and FinishDate if the timer finishes before it starts.
+// Returns true if the dates were swapped.
+func (timer *Timer) SwapDatesIfReversed() bool {
+	if timer.HasValidInterval() {
+		return false
+	}
+	timer.StartDate, timer.FinishDate = timer.FinishDate, timer.StartDate
+	return true
+}
+
+// Validate checks the timer for structural problems.
+// Returns an error describing every problem found, or nil if the timer is valid.
+func (timer *Timer) Validate() error {
+	var errs []error
+	if timer.StartDate.IsZero() {
+		errs = append(errs, errors.New("StartDate is not set"))
+	}
+	if timer.Finished && timer.FinishDate.IsZero() {
+		errs = append(errs, errors.New("Timer marked finished, but FinishDate is not set"))
+	}
+	if !timer.StartDate.IsZero() && !timer.FinishDate.IsZero() && !timer.FinishDate.After(timer.StartDate) {
+		errs = append(errs, errors.New("FinishDate is not after StartDate"))
+	}
+	for _, c := range timer.Contexts {
+		if strings.IndexFunc(c, unicode.IsSpace) >= 0 {
+			errs = append(errs, fmt.Errorf("Context %q contains whitespace", c))
+		}
+	}
+	for _, p := range timer.Projects {
+		if strings.IndexFunc(p, unicode.IsSpace) >= 0 {
+			errs = append(errs, fmt.Errorf("Project %q contains whitespace", p))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Duration returns how long the timer ran.
+// If the timer has no FinishDate, the duration up to time.Now() (or LastActive, see Touch()) is returned,
+// which is only the time elapsed so far, see IsRunning().
 func (timer *Timer) Duration() time.Duration {
-	end := time.Now()
+	return timer.DurationAsOf(time.Now())
+}
+
+// IsRunning returns true if the timer has no FinishDate, so its Duration keeps growing.
+// Unlike IsActive, a timer that starts in the future is also running.
+func (timer *Timer) IsRunning() bool {
+	return timer.FinishDate.IsZero()
+}
+
+// DurationAndRunning returns the Duration of the timer, and whether it is still running,
+// in which case the duration is the time elapsed so far rather than the final duration.
+func (timer *Timer) DurationAndRunning() (time.Duration, bool) {
+	return timer.Duration(), timer.IsRunning()
+}
+
+// DurationAsOf returns how long the timer ran.
+// If the timer has no FinishDate, the duration up to 'now' is returned, or up to LastActive if that is earlier.
+func (timer *Timer) DurationAsOf(now time.Time) time.Duration {
+	end := now
 	if !timer.FinishDate.IsZero() {
 		end = timer.FinishDate
+	} else if !timer.LastActive.IsZero() && timer.LastActive.Before(now) {
+		end = timer.LastActive
 	}
 	return end.Sub(timer.StartDate)
 }
 
+// Touch sets LastActive to time.Now(), recording that a running timer is still in use.
+// Until the timer is finished, its Duration runs up to the last Touch instead of the current time.
+// LastActive is written as a 'lastactive:' tag, so it survives writing and loading the timer.txt file.
+func (timer *Timer) Touch() {
+	timer.LastActive = time.Now()
+}
+
+// FinishedDuration returns how long the timer ran if it has a FinishDate, and 0 if it is still running.
+func (timer *Timer) FinishedDuration() time.Duration {
+	if timer.FinishDate.IsZero() {
+		return 0
+	}
+	return timer.FinishDate.Sub(timer.StartDate)
+}
+
+// DurationString returns the Duration of the timer formatted by FormatDuration.
+func (timer *Timer) DurationString() string {
+	return FormatDuration(timer.Duration())
+}
+
+// FormatDuration formats a duration as a compact string of days, hours and minutes, like "1d 3h" or "2h 15m".
+// Units that are zero are left out, durations under a minute are "<1m" and a zero duration is "0m".
+func FormatDuration(d time.Duration) string {
+	var sign string
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	if d == 0 {
+		return "0m"
+	} else if d < time.Minute {
+		return sign + "<1m"
+	}
+	days := int64(d / (24 * time.Hour))
+	hours := int64(d/time.Hour) % 24
+	minutes := int64(d/time.Minute) % 60
+	var parts []string
+	if days > 0 {
+		parts = append(parts, fmt.Sprintf("%dd", days))
+	}
+	if hours > 0 {
+		parts = append(parts, fmt.Sprintf("%dh", hours))
+	}
+	if minutes > 0 {
+		parts = append(parts, fmt.Sprintf("%dm", minutes))
+	}
+	return sign + strings.Join(parts, " ")
+}
+
+// RoundedDuration returns the Duration of the timer, rounded up to a multiple of increment.
+// This is useful for billing in fixed increments, e.g. a 7 minute timer is 15 minutes with a 15 minute increment.
+func (timer *Timer) RoundedDuration(increment time.Duration) time.Duration {
+	dur := timer.Duration()
+	if increment <= 0 {
+		return dur
+	}
+	rounded := dur.Truncate(increment)
+	if rounded < dur {
+		rounded += increment
+	}
+	return rounded
+}
+
+// NearestRoundedDuration returns the Duration of the timer, rounded to the nearest multiple of increment.
+func (timer *Timer) NearestRoundedDuration(increment time.Duration) time.Duration {
+	return timer.Duration().Round(increment)
+}
+
 func (timer *Timer) StartsToday() bool {
 	currTime := time.Now()
 	dur := int64(currTime.Hour())*int64(time.Hour) + int64(currTime.Minute())*int64(time.Minute)
@@ -177,19 +734,42 @@ func (timer *Timer) EndsToday() bool {
 	return int64(time.Since(timer.FinishDate)) < dur
 }
 
+// IsActive returns true if the timer is currently running: it has started, and isn't finished.
+// This is what "active" means throughout the package, e.g. for GetActiveTimers.
+func (timer *Timer) IsActive() bool {
+	return !timer.Finished && timer.FinishDate.IsZero() && !timer.StartDate.After(time.Now())
+}
+
+// ActiveToday returns true if the timer is active, or was running at any point today.
 func (timer *Timer) ActiveToday() bool {
-	return timer.ActiveOnDay(time.Now())
+	return timer.IsActive() || timer.ActiveOnDay(time.Now())
 }
 
+// ActiveOnDay returns true if the timer was running at any point on the day of t.
+// A timer without a FinishDate is treated as running until now.
 func (timer *Timer) ActiveOnDay(t time.Time) bool {
+	finish := timer.FinishDate
+	if finish.IsZero() {
+		finish = time.Now()
+	}
 	f := "2006/01/02"
 	tStr := t.Format(f)
 	// If StartDate or FinishDate is _on_ t, true
-	if timer.StartDate.Format(f) == tStr || timer.FinishDate.Format(f) == tStr {
+	if timer.StartDate.Format(f) == tStr || finish.Format(f) == tStr {
 		return true
 	}
 	// Otherwise, if StartDate is before t and FinishDate is after t
-	return timer.StartDate.Before(t) && timer.FinishDate.After(t)
+	return timer.StartDate.Before(t) && finish.After(t)
+}
+
+// ContainsTime returns true if the timer was running at the instant t, from StartDate to FinishDate inclusive.
+// A timer without a FinishDate is treated as running until now.
+func (timer *Timer) ContainsTime(t time.Time) bool {
+	finish := timer.FinishDate
+	if finish.IsZero() {
+		finish = time.Now()
+	}
+	return !t.Before(timer.StartDate) && !t.After(finish)
 }
 
 func (timer *Timer) HasContext(context string) bool {
@@ -209,3 +789,145 @@ func (timer *Timer) HasProject(project string) bool {
 	}
 	return false
 }
+
+// HasAllContexts returns true if the timer has every one of the given contexts.
+func (timer *Timer) HasAllContexts(contexts ...string) bool {
+	for _, c := range contexts {
+		if !timer.HasContext(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyContext returns true if the timer has at least one of the given contexts.
+func (timer *Timer) HasAnyContext(contexts ...string) bool {
+	for _, c := range contexts {
+		if timer.HasContext(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasAllProjects returns true if the timer has every one of the given projects.
+func (timer *Timer) HasAllProjects(projects ...string) bool {
+	for _, p := range projects {
+		if !timer.HasProject(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// HasAnyProject returns true if the timer has at least one of the given projects.
+func (timer *Timer) HasAnyProject(projects ...string) bool {
+	for _, p := range projects {
+		if timer.HasProject(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// AddContext adds the context to the timer, unless it is empty or the timer already has it.
+func (timer *Timer) AddContext(context string) {
+	if context != "" && !timer.HasContext(context) {
+		timer.Contexts = append(timer.Contexts, context)
+	}
+}
+
+// RemoveContext removes the context from the timer.
+// Returns false if the timer didn't have the context.
+func (timer *Timer) RemoveContext(context string) bool {
+	var found bool
+	timer.Contexts, found = removeString(timer.Contexts, context)
+	return found
+}
+
+// AddProject adds the project to the timer, unless it is empty or the timer already has it.
+func (timer *Timer) AddProject(project string) {
+	if project != "" && !timer.HasProject(project) {
+		timer.Projects = append(timer.Projects, project)
+	}
+}
+
+// RemoveProject removes the project from the timer.
+// Returns false if the timer didn't have the project.
+func (timer *Timer) RemoveProject(project string) bool {
+	var found bool
+	timer.Projects, found = removeString(timer.Projects, project)
+	return found
+}
+
+// removeString returns the slice without any occurrences of str, and whether str was found.
+func removeString(slice []string, str string) ([]string, bool) {
+	found := false
+	var ret []string
+	for _, v := range slice {
+		if v == str {
+			found = true
+		} else {
+			ret = append(ret, v)
+		}
+	}
+	return ret, found
+}
+
+// SetTag sets the additional tag 'key' to 'value', initializing AdditionalTags if needed.
+// Any other values of a repeated key are dropped.
+// Returns an error if the key is empty or contains whitespace or a colon, or if the value is empty or contains whitespace.
+func (timer *Timer) SetTag(key, value string) error {
+	if key == "" || strings.ContainsRune(key, ':') || strings.IndexFunc(key, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("Invalid tag key %q", key)
+	}
+	if value == "" || strings.IndexFunc(value, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("Invalid value %q for tag %q", value, key)
+	}
+	if timer.AdditionalTags == nil {
+		timer.AdditionalTags = make(map[string]string)
+	}
+	timer.AdditionalTags[key] = value
+	delete(timer.AdditionalTagsMulti, key)
+	return nil
+}
+
+// GetTag returns the value of the additional tag 'key', and whether the timer has it.
+func (timer *Timer) GetTag(key string) (string, bool) {
+	v, ok := timer.AdditionalTags[key]
+	return v, ok
+}
+
+// RemoveTag removes the additional tag 'key' from the timer, with all of its values.
+// Returns false if the timer didn't have the tag.
+func (timer *Timer) RemoveTag(key string) bool {
+	if _, ok := timer.AdditionalTags[key]; !ok {
+		return false
+	}
+	delete(timer.AdditionalTags, key)
+	delete(timer.AdditionalTagsMulti, key)
+	return true
+}
+
+// TagValues returns every value of the additional tag 'key', in the order they were parsed.
+// Returns nil if the timer doesn't have the tag.
+func (timer *Timer) TagValues(key string) []string {
+	v, ok := timer.AdditionalTags[key]
+	if !ok {
+		return nil
+	}
+	if multi := timer.AdditionalTagsMulti[key]; len(multi) > 0 && multi[len(multi)-1] == v {
+		return append([]string{}, multi...)
+	}
+	return []string{v}
+}
+
+func (timer *Timer) HasTag(key string) bool {
+	_, ok := timer.AdditionalTags[key]
+	return ok
+}
+
+func (timer *Timer) HasTagValue(key, value string) bool {
+	v, ok := timer.AdditionalTags[key]
+	return ok && v == value
+}
@@ -0,0 +1,853 @@
+package timertxt
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTimerWithTag(t *testing.T) {
+	timer, err := ParseTimer("2019-02-15T11:43:00-06:00 Fix bug due:tomorrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := timer.AdditionalTags["due"]; v != "tomorrow" {
+		t.Errorf("Expected due tag 'tomorrow', got %q", v)
+	}
+}
+
+func TestParseTimerWithoutTags(t *testing.T) {
+	timer, err := ParseTimer("2019-02-15T11:43:00-06:00 Fix bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.AdditionalTags == nil {
+		t.Fatal("Expected AdditionalTags to be initialized")
+	}
+	if len(timer.AdditionalTags) != 0 {
+		t.Errorf("Expected no additional tags, got %v", timer.AdditionalTags)
+	}
+	if NewTimer().AdditionalTags == nil {
+		t.Error("Expected NewTimer to initialize AdditionalTags")
+	}
+}
+
+func TestParseTimerOffsets(t *testing.T) {
+	tests := []struct {
+		in       string
+		expected time.Time
+		out      string
+	}{
+		{"2019-02-15T11:43:00-0600 Task", time.Date(2019, 2, 15, 17, 43, 0, 0, time.UTC), "2019-02-15T11:43:00-06:00 Task"},
+		{"2019-02-15T11:43:00-06:00 Task", time.Date(2019, 2, 15, 17, 43, 0, 0, time.UTC), "2019-02-15T11:43:00-06:00 Task"},
+		{"2019-02-15T11:43:00Z Task", time.Date(2019, 2, 15, 11, 43, 0, 0, time.UTC), "2019-02-15T11:43:00Z Task"},
+	}
+	for _, tt := range tests {
+		timer, err := ParseTimer(tt.in)
+		if err != nil {
+			t.Errorf("%q: %v", tt.in, err)
+			continue
+		}
+		if !timer.StartDate.Equal(tt.expected) {
+			t.Errorf("%q: Expected StartDate %v, got %v", tt.in, tt.expected, timer.StartDate)
+		}
+		if s := timer.String(); s != tt.out {
+			t.Errorf("%q: Expected String() %q, got %q", tt.in, tt.out, s)
+		}
+	}
+}
+
+func TestTagValuesWithColons(t *testing.T) {
+	tests := []struct {
+		key, value string
+	}{
+		{"url", "https://example.com/a?b=c"},
+		{"due", "2019-02-15T11:43:00"},
+	}
+	for _, tt := range tests {
+		line := "2019-02-15T11:43:00-06:00 Task " + tt.key + ":" + tt.value
+		timer, err := ParseTimer(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := timer.AdditionalTags[tt.key]; v != tt.value {
+			t.Errorf("Expected %s tag %q, got %q", tt.key, tt.value, v)
+		}
+		reparsed, err := ParseTimer(timer.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := reparsed.AdditionalTags[tt.key]; v != tt.value {
+			t.Errorf("Expected %s tag %q after round trip, got %q", tt.key, tt.value, v)
+		}
+	}
+}
+
+func TestTimerJSONRoundTrip(t *testing.T) {
+	timer, err := ParseTimer("x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Creating Go Library Repo @home @personal +timertxt customTag1:Important! due:Today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(timer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Timer
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.String() != timer.String() {
+		t.Errorf("Expected %q after round trip, got %q", timer.String(), decoded.String())
+	}
+}
+
+func TestTimerJSONOmitsFinishDate(t *testing.T) {
+	timer, err := ParseTimer("2019-02-15T11:43:00-06:00 Open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(timer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fields["finish_date"]; ok {
+		t.Errorf("Expected no finish_date, got %s", data)
+	}
+}
+
+func TestTimerJSONRejectsFinishedWithoutDate(t *testing.T) {
+	var timer Timer
+	err := json.Unmarshal([]byte(`{"start_date":"2019-02-15T11:43:00-06:00","finished":true}`), &timer)
+	if err == nil {
+		t.Error("Expected an error for a finished timer without finish_date")
+	}
+}
+
+func TestDurationAsOf(t *testing.T) {
+	now := time.Date(2019, 2, 15, 12, 0, 0, 0, time.UTC)
+	finished, err := ParseTimer("x 2019-02-15T06:00:00Z 2019-02-15T10:30:00Z Done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := finished.DurationAsOf(now); d != 4*time.Hour+30*time.Minute {
+		t.Errorf("Expected 4h30m for the finished timer, got %v", d)
+	}
+	active, err := ParseTimer("2019-02-15T11:15:00Z Open")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := active.DurationAsOf(now); d != 45*time.Minute {
+		t.Errorf("Expected 45m for the active timer, got %v", d)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	start := time.Date(2019, 2, 15, 6, 0, 0, 0, time.UTC)
+	valid := Timer{StartDate: start, FinishDate: start.Add(time.Hour), Finished: true, Contexts: []string{"home"}}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected a valid timer, got %v", err)
+	}
+	finishedWithoutDate := Timer{StartDate: start, Finished: true}
+	if err := finishedWithoutDate.Validate(); err == nil {
+		t.Error("Expected an error for a finished timer without FinishDate")
+	}
+	reversed := Timer{StartDate: start, FinishDate: start.Add(-time.Hour), Finished: true}
+	if err := reversed.Validate(); err == nil {
+		t.Error("Expected an error for a timer finishing before it starts")
+	}
+	several := Timer{Finished: true, Projects: []string{"my project"}}
+	if err := several.Validate(); err == nil || strings.Count(err.Error(), "\n") != 2 {
+		t.Errorf("Expected three problems, got %v", err)
+	}
+}
+
+func TestParseTimerEmptyInput(t *testing.T) {
+	for _, text := range []string{"", "   ", "x", "x (A)"} {
+		if _, err := ParseTimer(text); err == nil {
+			t.Errorf("%q: Expected an error", text)
+		}
+	}
+}
+
+func TestPriority(t *testing.T) {
+	tests := []struct {
+		in, priority string
+	}{
+		{"(A) 2019-02-15T11:43:00-06:00 Important", "A"},
+		{"x (B) 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done", "B"},
+		{"2019-02-15T11:43:00-06:00 No priority", ""},
+	}
+	for _, tt := range tests {
+		timer, err := ParseTimer(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if timer.Priority != tt.priority {
+			t.Errorf("%q: Expected priority %q, got %q", tt.in, tt.priority, timer.Priority)
+		}
+		if s := timer.String(); s != tt.in {
+			t.Errorf("Expected %q, got %q", tt.in, s)
+		}
+	}
+}
+
+func TestFinishedMatchesFinishDate(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Marked",
+		"2019-02-15T08:00:00Z 2019-02-15T09:00:00Z Unmarked",
+		"2019-02-15T10:00:00Z Open",
+	}, "\n"))
+	for _, timer := range timerlist {
+		if timer.Finished == timer.FinishDate.IsZero() {
+			t.Errorf("%s: Finished is %v but FinishDate is %v", timer.Notes, timer.Finished, timer.FinishDate)
+		}
+	}
+	if !timerlist[1].Finished {
+		t.Error("Expected a finish date without 'x' to mark the timer finished")
+	}
+	active := timerlist.GetActiveTimers()
+	if len(*active) != 1 || (*active)[0].Finished {
+		t.Errorf("Expected GetActiveTimers to return only the unfinished timer, got %v", active)
+	}
+	timerlist[0].Reopen()
+	if timerlist[0].Finished || !timerlist[0].FinishDate.IsZero() {
+		t.Errorf("Expected Reopen to clear Finished and FinishDate, got %v", timerlist[0])
+	}
+}
+
+func TestTimerEqual(t *testing.T) {
+	a, err := ParseTimer("2019-02-15T11:43:00-06:00 Work @home @office +timertxt due:tomorrow billable:yes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ParseTimer("2019-02-15T11:43:00-06:00   Work @office +timertxt @home billable:yes due:tomorrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.Id, b.Id = 1, 2
+	if !a.Equal(*b) {
+		t.Errorf("Expected %v to equal %v", a, b)
+	}
+	b.AddContext("phone")
+	if a.Equal(*b) {
+		t.Errorf("Expected %v not to equal %v", a, b)
+	}
+}
+
+func TestTimerClone(t *testing.T) {
+	timer, err := ParseTimer("2019-02-15T11:43:00-06:00 Work @home +timertxt due:tomorrow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clone := timer.Clone()
+	clone.AdditionalTags["due"] = "today"
+	clone.Contexts[0] = "office"
+	clone.Projects = append(clone.Projects, "other")
+	if timer.AdditionalTags["due"] != "tomorrow" || timer.Contexts[0] != "home" || len(timer.Projects) != 1 {
+		t.Errorf("Expected the source to be unchanged, got %v", timer)
+	}
+}
+
+func TestAddRemoveContextsAndProjects(t *testing.T) {
+	timer, err := ParseTimer("2019-02-15T11:43:00-06:00 Work @home +timertxt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timer.AddContext("home")
+	timer.AddContext("")
+	timer.AddContext("office")
+	timer.AddProject("timertxt")
+	if s := timer.String(); s != "2019-02-15T11:43:00-06:00 Work @home @office +timertxt" {
+		t.Errorf("Unexpected timer %q", s)
+	}
+	if timer.RemoveProject("missing") {
+		t.Error("Expected removing a missing project to return false")
+	}
+	if !timer.RemoveContext("home") || timer.HasContext("home") {
+		t.Error("Expected @home to be removed")
+	}
+}
+
+func TestSetTagOnEmptyTimer(t *testing.T) {
+	var timer Timer
+	if err := timer.SetTag("due", "tomorrow"); err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := timer.GetTag("due"); !ok || v != "tomorrow" {
+		t.Errorf("Expected due:tomorrow, got %q, %v", v, ok)
+	}
+	for _, key := range []string{"", "a b", "a:b"} {
+		if err := timer.SetTag(key, "x"); err == nil {
+			t.Errorf("%q: Expected an invalid key error", key)
+		}
+	}
+	if !timer.RemoveTag("due") || timer.RemoveTag("due") {
+		t.Error("Expected RemoveTag to report whether the tag was there")
+	}
+	var empty Timer
+	if _, ok := empty.GetTag("due"); ok || empty.RemoveTag("due") {
+		t.Error("Expected no tags on an empty timer")
+	}
+}
+
+func TestActiveOnDayOpenTimer(t *testing.T) {
+	now := time.Now()
+	timer := Timer{StartDate: now.AddDate(0, 0, -2)}
+	if !timer.ActiveOnDay(now) {
+		t.Error("Expected an open timer started two days ago to be active today")
+	}
+	if !timer.ActiveOnDay(now.AddDate(0, 0, -1)) {
+		t.Error("Expected an open timer started two days ago to be active yesterday")
+	}
+	if timer.ActiveOnDay(now.AddDate(0, 0, -3)) {
+		t.Error("Expected the timer not to be active the day before it started")
+	}
+}
+
+func TestNotesWithColons(t *testing.T) {
+	text := "2019-02-15T11:43:00-06:00 Mix at ratio 3:1 until 10:30 due:today"
+	timer, err := ParseTimer(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.Notes != "Mix at ratio 3:1 until 10:30" {
+		t.Errorf("Expected the colon words in Notes, got %q", timer.Notes)
+	}
+	if len(timer.AdditionalTags) != 1 || timer.AdditionalTags["due"] != "today" {
+		t.Errorf("Expected only due:today as a tag, got %v", timer.AdditionalTags)
+	}
+	if s := timer.String(); s != text {
+		t.Errorf("Expected %q, got %q", text, s)
+	}
+}
+
+func TestParseTimerDocExamples(t *testing.T) {
+	zone := time.FixedZone("", -6*60*60)
+	tests := []struct {
+		in            string
+		start, finish time.Time
+		notes         string
+	}{
+		{
+			"2019-02-15T11:43:00-06:00 Working on Go Library @home @personal +timertxt customTag1:Important! due:Today",
+			time.Date(2019, 2, 15, 11, 43, 0, 0, zone), time.Time{}, "Working on Go Library",
+		},
+		{
+			"x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Creating Go Library Repo @home @personal +timertxt customTag1:Important! due:Today",
+			time.Date(2019, 2, 15, 6, 0, 0, 0, zone), time.Date(2019, 2, 15, 10, 0, 0, 0, zone), "Creating Go Library Repo",
+		},
+	}
+	for _, tt := range tests {
+		timer, err := ParseTimer(tt.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !timer.StartDate.Equal(tt.start) || !timer.FinishDate.Equal(tt.finish) || timer.Finished == tt.finish.IsZero() {
+			t.Errorf("%q: Unexpected dates %v - %v (finished %v)", tt.in, timer.StartDate, timer.FinishDate, timer.Finished)
+		}
+		if timer.Notes != tt.notes || timer.Priority != "" {
+			t.Errorf("%q: Unexpected Notes %q or Priority %q", tt.in, timer.Notes, timer.Priority)
+		}
+		if strings.Join(timer.Contexts, " ") != "home personal" || strings.Join(timer.Projects, " ") != "timertxt" {
+			t.Errorf("%q: Unexpected Contexts %v or Projects %v", tt.in, timer.Contexts, timer.Projects)
+		}
+		if len(timer.AdditionalTags) != 2 || timer.AdditionalTags["customTag1"] != "Important!" || timer.AdditionalTags["due"] != "Today" {
+			t.Errorf("%q: Unexpected tags %v", tt.in, timer.AdditionalTags)
+		}
+		if s := timer.String(); s != tt.in {
+			t.Errorf("Expected %q, got %q", tt.in, s)
+		}
+	}
+}
+
+func TestRaw(t *testing.T) {
+	text := "2019-02-15T11:43:00-06:00   Work @office +timertxt @home due:today billable:yes"
+	timer, err := ParseTimer(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.Raw() != text {
+		t.Errorf("Expected Raw() %q, got %q", text, timer.Raw())
+	}
+	if s := timer.String(); s != "2019-02-15T11:43:00-06:00 Work @home @office +timertxt billable:yes due:today" {
+		t.Errorf("Expected String() to sort the tags, got %q", s)
+	}
+	built := Timer{StartDate: timer.StartDate, Notes: "Built"}
+	if built.Raw() != built.String() {
+		t.Errorf("Expected Raw() to fall back to String(), got %q", built.Raw())
+	}
+}
+
+func TestSplit(t *testing.T) {
+	timer, err := ParseTimer("x 2019-02-15T08:00:00Z 2019-02-15T10:00:00Z Work @home due:today")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second, err := timer.Split(time.Date(2019, 2, 15, 9, 15, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := first.String(); s != "x 2019-02-15T08:00:00Z 2019-02-15T09:15:00Z Work @home due:today" {
+		t.Errorf("Unexpected first part %q", s)
+	}
+	if s := second.String(); s != "x 2019-02-15T09:15:00Z 2019-02-15T10:00:00Z Work @home due:today" {
+		t.Errorf("Unexpected second part %q", s)
+	}
+	for _, at := range []time.Time{timer.StartDate, timer.FinishDate, timer.StartDate.Add(-time.Hour), timer.FinishDate.Add(time.Hour)} {
+		if _, _, err := timer.Split(at); err == nil {
+			t.Errorf("%v: Expected an error splitting outside the timer", at)
+		}
+	}
+	open := Timer{StartDate: time.Now().Add(-time.Hour)}
+	_, second, err = open.Split(time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Finished || !second.FinishDate.IsZero() {
+		t.Errorf("Expected the second part of an open timer to stay open, got %v", second)
+	}
+}
+
+func TestInLocation(t *testing.T) {
+	timer, err := ParseTimer("x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	utc := timer.InLocation(time.UTC)
+	if s := utc.String(); s != "x 2019-02-15T12:00:00Z 2019-02-15T16:00:00Z Work" {
+		t.Errorf("Unexpected timer %q", s)
+	}
+	if !utc.StartDate.Equal(timer.StartDate) || !utc.FinishDate.Equal(timer.FinishDate) {
+		t.Error("Expected the instants to be unchanged")
+	}
+	if s := timer.String(); s != "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Work" {
+		t.Errorf("Expected the source to be unchanged, got %q", s)
+	}
+	open := Timer{StartDate: timer.StartDate}
+	if converted := open.InLocation(time.UTC); !converted.FinishDate.IsZero() {
+		t.Errorf("Expected an open timer to stay open, got %v", converted)
+	}
+}
+
+func TestDurationTag(t *testing.T) {
+	timer, err := ParseTimer("x 2019-02-15T06:00:00Z 2019-02-15T07:30:00Z Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := timer.String(); strings.Contains(s, "dur:") {
+		t.Errorf("Expected no dur tag by default, got %q", s)
+	}
+	timer.EmitDurationTag = true
+	want := "x 2019-02-15T06:00:00Z 2019-02-15T07:30:00Z Work dur:01:30"
+	if s := timer.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+	reparsed, err := ParseTimer(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reparsed.HasTag("dur") || !reparsed.EmitDurationTag {
+		t.Errorf("Expected the computed dur tag to be dropped on parse, got %v", reparsed.AdditionalTags)
+	}
+	if s := reparsed.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+	// A dur tag that doesn't match the timer is user data and kept
+	other, err := ParseTimer("x 2019-02-15T06:00:00Z 2019-02-15T07:30:00Z Work dur:02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := other.GetTag("dur"); v != "02:00" || other.EmitDurationTag {
+		t.Errorf("Expected dur:02:00 to be kept, got %v", other.AdditionalTags)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{75 * time.Minute, "1h 15m"},
+		{26 * time.Hour, "1d 2h"},
+		{0, "0m"},
+		{30 * time.Second, "<1m"},
+		{-2 * time.Hour, "-2h"},
+	}
+	for _, tt := range tests {
+		if s := FormatDuration(tt.d); s != tt.want {
+			t.Errorf("%v: Expected %q, got %q", tt.d, tt.want, s)
+		}
+	}
+	timer, err := ParseTimer("x 2019-02-15T06:00:00Z 2019-02-15T08:15:00Z Work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := timer.DurationString(); s != "2h 15m" {
+		t.Errorf("Expected 2h 15m, got %q", s)
+	}
+}
+
+func TestFinishedMarkerWithoutSpace(t *testing.T) {
+	want := "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done"
+	for _, text := range []string{want, "x2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done"} {
+		timer, err := ParseTimer(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !timer.Finished || timer.Notes != "Done" {
+			t.Errorf("%q: Expected a finished timer, got %v", text, timer)
+		}
+		if s := timer.String(); s != want {
+			t.Errorf("%q: Expected %q, got %q", text, want, s)
+		}
+	}
+	// Only a date directly after the 'x' is split off
+	timer, err := ParseTimer("2019-02-15T06:00:00-06:00 xylophone practice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.Finished || timer.Notes != "xylophone practice" {
+		t.Errorf("Expected an unfinished timer, got %v", timer)
+	}
+}
+
+func TestIsActive(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		timer  Timer
+		active bool
+	}{
+		{"running", Timer{StartDate: now.Add(-time.Hour)}, true},
+		{"future", Timer{StartDate: now.Add(time.Hour)}, false},
+		{"finished", Timer{StartDate: now.Add(-2 * time.Hour), FinishDate: now.Add(-time.Hour), Finished: true}, false},
+	}
+	for _, tt := range tests {
+		if tt.timer.IsActive() != tt.active {
+			t.Errorf("%s: Expected IsActive %v", tt.name, tt.active)
+		}
+		if tt.timer.IsActive() && !tt.timer.ActiveToday() {
+			t.Errorf("%s: Expected an active timer to be active today", tt.name)
+		}
+	}
+}
+
+func TestOverlaps(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2019, 2, 15, hour, 0, 0, 0, time.UTC)
+	}
+	span := func(start, finish int) Timer {
+		return Timer{StartDate: at(start), FinishDate: at(finish), Finished: true}
+	}
+	tests := []struct {
+		name     string
+		a, b     Timer
+		overlaps bool
+	}{
+		{"overlapping", span(8, 10), span(9, 11), true},
+		{"contained", span(8, 12), span(9, 10), true},
+		{"touching", span(8, 10), span(10, 11), false},
+		{"disjoint", span(8, 9), span(10, 11), false},
+		{"identical instants", span(8, 8), span(8, 8), true},
+		{"open", Timer{StartDate: at(8)}, span(9, 10), true},
+	}
+	for _, tt := range tests {
+		if tt.a.Overlaps(tt.b) != tt.overlaps || tt.b.Overlaps(tt.a) != tt.overlaps {
+			t.Errorf("%s: Expected Overlaps %v", tt.name, tt.overlaps)
+		}
+	}
+}
+
+func BenchmarkTimerString(b *testing.B) {
+	timer, err := ParseTimer("x (A) 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Creating Go Library Repo " +
+		"@home @personal @office @phone @laptop +timertxt +golang +library +oss " +
+		"customTag1:Important! due:Today billable:yes client:acme rate:100")
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = timer.String()
+	}
+}
+
+func TestStringKeepsContextOrder(t *testing.T) {
+	timer := Timer{
+		StartDate: time.Date(2019, 2, 15, 8, 0, 0, 0, time.UTC),
+		Notes:     "Work",
+		Contexts:  []string{"office", "home"},
+		Projects:  []string{"zeta", "alpha"},
+	}
+	want := "2019-02-15T08:00:00Z Work @home @office +alpha +zeta"
+	for i := 0; i < 2; i++ {
+		if s := timer.String(); s != want {
+			t.Errorf("Expected %q, got %q", want, s)
+		}
+	}
+	if timer.Contexts[0] != "office" || timer.Projects[0] != "zeta" {
+		t.Errorf("Expected String() not to sort the timer's slices, got %v and %v", timer.Contexts, timer.Projects)
+	}
+}
+
+func TestTextMarshalerRoundTrip(t *testing.T) {
+	text := "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done @home +timertxt due:today"
+	timer, err := ParseTimer(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var m encoding.TextMarshaler = timer
+	data, err := m.MarshalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != text {
+		t.Errorf("Expected %q, got %q", text, data)
+	}
+	var parsed Timer
+	var u encoding.TextUnmarshaler = &parsed
+	if err := u.UnmarshalText(data); err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.Equal(*timer) || parsed.String() != text {
+		t.Errorf("Expected %q, got %q", text, parsed.String())
+	}
+	if err := parsed.UnmarshalText([]byte("not a timer")); err == nil {
+		t.Error("Expected an error for invalid text")
+	}
+}
+
+func TestFluentBuilder(t *testing.T) {
+	timer := NewTimer().
+		WithStart(time.Date(2019, 2, 15, 11, 43, 0, 0, time.UTC)).
+		WithNotes("Working on Go Library").
+		WithContext("personal").
+		WithContext("home").
+		WithContext("home").
+		WithProject("timertxt").
+		WithTag("due", "Today").
+		WithTag("bad key", "ignored")
+	want := "2019-02-15T11:43:00Z Working on Go Library @home @personal +timertxt due:Today"
+	if s := timer.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+}
+
+func TestParseTimerStrict(t *testing.T) {
+	text := "2019-02-15T11:43:00-06:00 Work due:a due:b"
+	timer, err := ParseTimer(text)
+	if err != nil {
+		t.Fatalf("Expected the lenient default to accept duplicate tags, got %v", err)
+	}
+	if v, _ := timer.GetTag("due"); v != "b" {
+		t.Errorf("Expected the last value to win, got %q", v)
+	}
+	for _, text := range []string{text, "2019-02-15T11:43:00-06:00 Work due:", "2019-02-15T11:43:00-06:00 Work :today"} {
+		_, err := ParseTimerStrict(text)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: Expected a ParseError, got %v", text, err)
+		}
+	}
+	if _, err := ParseTimerStrict("2019-02-15T11:43:00-06:00 Work due:a ratio 3:1"); err != nil {
+		t.Errorf("Expected a valid timer to pass strict parsing, got %v", err)
+	}
+}
+
+func TestRepeatedTags(t *testing.T) {
+	text := "2019-02-15T11:43:00-06:00 Work due:today ref:1 ref:2"
+	timer, err := ParseTimer(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := timer.GetTag("ref"); v != "2" {
+		t.Errorf("Expected the last value in AdditionalTags, got %q", v)
+	}
+	if got := strings.Join(timer.TagValues("ref"), " "); got != "1 2" {
+		t.Errorf("Expected both values, got %q", got)
+	}
+	if s := timer.String(); s != text {
+		t.Errorf("Expected %q, got %q", text, s)
+	}
+	data, err := json.Marshal(timer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded Timer
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if s := decoded.String(); s != text {
+		t.Errorf("Expected the repeated tag to survive JSON, got %q", s)
+	}
+	if err := timer.SetTag("ref", "3"); err != nil {
+		t.Fatal(err)
+	}
+	if s := timer.String(); s != "2019-02-15T11:43:00-06:00 Work due:today ref:3" {
+		t.Errorf("Expected SetTag to replace every value, got %q", s)
+	}
+	timer.AdditionalTagsMulti = map[string][]string{"ref": {"1", "3"}}
+	timer.RemoveTag("ref")
+	if timer.TagValues("ref") != nil || timer.AdditionalTagsMulti["ref"] != nil {
+		t.Errorf("Expected RemoveTag to drop every value, got %v", timer.AdditionalTagsMulti)
+	}
+}
+
+func TestFinishAt(t *testing.T) {
+	start := time.Date(2019, 2, 15, 8, 0, 0, 0, time.UTC)
+	timer := Timer{StartDate: start}
+	if err := timer.FinishAt(start.Add(-time.Minute)); err == nil {
+		t.Error("Expected an error finishing before the start")
+	}
+	if timer.Finished || !timer.FinishDate.IsZero() {
+		t.Errorf("Expected the timer to be unchanged, got %v", timer)
+	}
+	finish := start.Add(90 * time.Minute)
+	if err := timer.FinishAt(finish); err != nil {
+		t.Fatal(err)
+	}
+	if !timer.Finished || !timer.FinishDate.Equal(finish) || timer.Duration() != 90*time.Minute {
+		t.Errorf("Expected the timer finished after 90m, got %v", timer)
+	}
+	// Finish always uses the current time, even for a timer that starts in the future
+	future := Timer{StartDate: time.Now().Add(time.Hour)}
+	future.Finish()
+	if !future.Finished || future.FinishDate.IsZero() {
+		t.Errorf("Expected Finish to finish the timer, got %v", future)
+	}
+}
+
+func TestSetStartDate(t *testing.T) {
+	start := time.Date(2019, 2, 15, 8, 0, 0, 0, time.UTC)
+	timer := Timer{StartDate: start, FinishDate: start.Add(time.Hour), Finished: true}
+	if err := timer.SetStartDate(start.Add(30 * time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if timer.Duration() != 30*time.Minute {
+		t.Errorf("Expected 30m, got %v", timer.Duration())
+	}
+	if err := timer.SetStartDate(start.Add(2 * time.Hour)); err == nil {
+		t.Error("Expected an error starting after the finish")
+	}
+	if !timer.StartDate.Equal(start.Add(30 * time.Minute)) {
+		t.Errorf("Expected the timer to be unchanged, got %v", timer)
+	}
+	open := Timer{StartDate: start}
+	if err := open.SetStartDate(start.Add(24 * time.Hour)); err != nil {
+		t.Errorf("Expected any start date for an open timer, got %v", err)
+	}
+}
+
+func TestIsRunning(t *testing.T) {
+	finished := Timer{StartDate: time.Now().Add(-2 * time.Hour), FinishDate: time.Now().Add(-time.Hour), Finished: true}
+	if d, running := finished.DurationAndRunning(); running || d != finished.FinishDate.Sub(finished.StartDate) {
+		t.Errorf("Expected a finished timer not to be running, got %v, %v", d, running)
+	}
+	open := Timer{StartDate: time.Now().Add(-time.Hour)}
+	if d, running := open.DurationAndRunning(); !running || d < time.Hour {
+		t.Errorf("Expected an open timer to be running, got %v, %v", d, running)
+	}
+	future := Timer{StartDate: time.Now().Add(time.Hour)}
+	if !future.IsRunning() || future.IsActive() {
+		t.Error("Expected a future timer to be running but not active")
+	}
+}
+
+func TestParseErrorField(t *testing.T) {
+	tests := []struct {
+		in, field string
+	}{
+		{"", "Text"},
+		{"not-a-date Work", "StartDate"},
+		{"x 2019-02-15T06:00:00-06:00", "FinishDate"},
+		{"x 2019-02-15T06:00:00-06:00 not-a-date Work", "FinishDate"},
+	}
+	for _, tt := range tests {
+		_, err := ParseTimer(tt.in)
+		var perr *ParseError
+		if !errors.As(err, &perr) {
+			t.Errorf("%q: Expected a ParseError, got %v", tt.in, err)
+			continue
+		}
+		if perr.Field != tt.field || perr.Text != tt.in || perr.Err == nil {
+			t.Errorf("%q: Expected Field %q, got %+v", tt.in, tt.field, perr)
+		}
+	}
+	// Load errors wrap the ParseError
+	var timerlist TimerList
+	err := timerlist.LoadFrom(strings.NewReader("2019-02-15T08:00:00Z First\nnot-a-date Work\n"))
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Field != "StartDate" {
+		t.Errorf("Expected a StartDate ParseError, got %v", err)
+	}
+}
+
+func TestContainsTime(t *testing.T) {
+	start := time.Date(2019, 2, 15, 8, 0, 0, 0, time.UTC)
+	timer := Timer{StartDate: start, FinishDate: start.Add(time.Hour), Finished: true}
+	tests := []struct {
+		at   time.Time
+		want bool
+	}{
+		{start, true},
+		{start.Add(30 * time.Minute), true},
+		{start.Add(time.Hour), true},
+		{start.Add(-time.Second), false},
+		{start.Add(time.Hour + time.Second), false},
+	}
+	for _, tt := range tests {
+		if timer.ContainsTime(tt.at) != tt.want {
+			t.Errorf("%v: Expected ContainsTime %v", tt.at, tt.want)
+		}
+	}
+	open := Timer{StartDate: time.Now().Add(-time.Hour)}
+	if !open.ContainsTime(time.Now().Add(-time.Minute)) || open.ContainsTime(time.Now().Add(time.Hour)) {
+		t.Error("Expected an open timer to run up to now")
+	}
+}
+
+func TestTouch(t *testing.T) {
+	open := Timer{StartDate: time.Now().Add(-time.Hour)}
+	open.Touch()
+	touched := open.Duration()
+	time.Sleep(10 * time.Millisecond)
+	if d := open.Duration(); d != touched {
+		t.Errorf("Expected the duration to stop at the last Touch, got %v and %v", touched, d)
+	}
+	untouched := Timer{StartDate: open.StartDate}
+	if d := untouched.Duration(); d <= touched {
+		t.Errorf("Expected an untouched timer to run up to now, got %v", d)
+	}
+	// A LastActive in the future doesn't extend the duration past now
+	future := Timer{StartDate: open.StartDate, LastActive: time.Now().Add(time.Hour)}
+	if d := future.Duration(); d > 2*time.Hour || d < time.Hour {
+		t.Errorf("Expected about an hour, got %v", d)
+	}
+}
+
+func TestLastActiveRoundTrip(t *testing.T) {
+	text := "2019-02-15T08:00:00Z Work lastactive:2019-02-15T08:45:00Z"
+	timer, err := ParseTimer(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timer.HasTag("lastactive") || timer.LastActive.IsZero() || timer.Duration() != 45*time.Minute {
+		t.Errorf("Expected LastActive to be read from the tag, got %v", timer.LastActive)
+	}
+	if s := timer.String(); s != text {
+		t.Errorf("Expected %q, got %q", text, s)
+	}
+	if err := timer.FinishAt(timer.StartDate.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if s := timer.String(); strings.Contains(s, "lastactive") {
+		t.Errorf("Expected no lastactive tag on a finished timer, got %q", s)
+	}
+}
@@ -2,16 +2,23 @@ package timertxt
 
 import (
 	"bufio"
+	"compress/gzip"
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
+	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // TimerList represents a list of timer.txt timer entries.
 // It is usually loasded from a whole timer.txt file.
+// A TimerList is not safe for concurrent use, see SyncTimerList for that.
 type TimerList []Timer
 
 // NewTimerList creates a new empty TimerList.
@@ -19,12 +26,14 @@ func NewTimerList() *TimerList {
 	return &TimerList{}
 }
 
+// GetTimersInRange returns the timers that started or finished between start and end.
+// Both boundaries are inclusive, and timers without a FinishDate only match on their StartDate.
 func (timerlist *TimerList) GetTimersInRange(start, end time.Time) *TimerList {
 	fltr := func(t Timer) bool {
-		if t.StartDate.Before(end) && t.StartDate.After(start) {
+		if inRange(t.StartDate, start, end) {
 			return true
 		}
-		if t.FinishDate.Before(end) && t.FinishDate.After(start) {
+		if !t.FinishDate.IsZero() && inRange(t.FinishDate, start, end) {
 			return true
 		}
 		return false
@@ -32,6 +41,70 @@ func (timerlist *TimerList) GetTimersInRange(start, end time.Time) *TimerList {
 	return timerlist.Filter(fltr)
 }
 
+// GetTimersStartedBetween returns the timers whose StartDate is between start and end, inclusive.
+func (timerlist *TimerList) GetTimersStartedBetween(start, end time.Time) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return inRange(t.StartDate, start, end)
+	})
+}
+
+// GetTimersFinishedBetween returns the timers whose FinishDate is between start and end, inclusive.
+// Unfinished timers never match.
+func (timerlist *TimerList) GetTimersFinishedBetween(start, end time.Time) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return !t.FinishDate.IsZero() && inRange(t.FinishDate, start, end)
+	})
+}
+
+// GetTimersSince returns the timers that started within the last 'd', up to now.
+func (timerlist *TimerList) GetTimersSince(d time.Duration) *TimerList {
+	return timerlist.getTimersSince(d, time.Now())
+}
+
+// GetTimersToday returns the timers that started today, in local time.
+func (timerlist *TimerList) GetTimersToday() *TimerList {
+	return timerlist.getTimersToday(time.Now())
+}
+
+// GetTimersThisWeek returns the timers that started this week, in local time. Weeks start on Monday.
+func (timerlist *TimerList) GetTimersThisWeek() *TimerList {
+	return timerlist.getTimersThisWeek(time.Now())
+}
+
+func (timerlist *TimerList) getTimersSince(d time.Duration, now time.Time) *TimerList {
+	return timerlist.GetTimersStartedBetween(now.Add(-d), now)
+}
+
+func (timerlist *TimerList) getTimersToday(now time.Time) *TimerList {
+	start := startOfDay(now)
+	return timerlist.GetTimersStartedBetween(start, start.AddDate(0, 0, 1).Add(-time.Nanosecond))
+}
+
+func (timerlist *TimerList) getTimersThisWeek(now time.Time) *TimerList {
+	// Days since Monday
+	offset := (int(now.Weekday()) + 6) % 7
+	start := startOfDay(now).AddDate(0, 0, -offset)
+	return timerlist.GetTimersStartedBetween(start, start.AddDate(0, 0, 7).Add(-time.Nanosecond))
+}
+
+// startOfDay returns midnight of the day of t, in local time.
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.In(time.Local).Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// inRange reports whether d is between start and end, inclusive.
+func inRange(d, start, end time.Time) bool {
+	return !d.Before(start) && !d.After(end)
+}
+
+// GetTimersOnDay returns the timers that were active on the day of t, see *Timer.ActiveOnDay().
+func (timerlist *TimerList) GetTimersOnDay(t time.Time) *TimerList {
+	return timerlist.Filter(func(timer Timer) bool {
+		return timer.ActiveOnDay(t)
+	})
+}
+
 func (timerlist *TimerList) GetTimersWithContext(context string) *TimerList {
 	return timerlist.Filter(func(t Timer) bool {
 		return t.HasContext(context)
@@ -44,32 +117,223 @@ func (timerlist *TimerList) GetTimersWithProject(project string) *TimerList {
 	})
 }
 
+func (timerlist *TimerList) GetTimersWithAllContexts(contexts ...string) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return t.HasAllContexts(contexts...)
+	})
+}
+
+func (timerlist *TimerList) GetTimersWithAnyContext(contexts ...string) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return t.HasAnyContext(contexts...)
+	})
+}
+
+func (timerlist *TimerList) GetTimersWithAllProjects(projects ...string) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return t.HasAllProjects(projects...)
+	})
+}
+
+func (timerlist *TimerList) GetTimersWithAnyProject(projects ...string) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return t.HasAnyProject(projects...)
+	})
+}
+
+func (timerlist *TimerList) GetTimersWithTag(key string) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return t.HasTag(key)
+	})
+}
+
+func (timerlist *TimerList) GetTimersWithTagValue(key, value string) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return t.HasTagValue(key, value)
+	})
+}
+
+// GetActiveTimers returns the timers that are currently running, see *Timer.IsActive().
 func (timerlist *TimerList) GetActiveTimers() *TimerList {
-	t := *NewTimerList()
-	for _, v := range *timerlist {
-		if v.FinishDate.IsZero() {
-			t = append(t, v)
+	return timerlist.Filter(func(t Timer) bool {
+		return t.IsActive()
+	})
+}
+
+// GetFinishedTimers returns the timers that have a FinishDate.
+func (timerlist *TimerList) GetFinishedTimers() *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return !t.FinishDate.IsZero()
+	})
+}
+
+// Projects returns the distinct projects used in the TimerList, sorted alphabetically.
+func (timerlist *TimerList) Projects() []string {
+	return timerlist.distinct(func(t Timer) []string {
+		return t.Projects
+	})
+}
+
+// Contexts returns the distinct contexts used in the TimerList, sorted alphabetically.
+func (timerlist *TimerList) Contexts() []string {
+	return timerlist.distinct(func(t Timer) []string {
+		return t.Contexts
+	})
+}
+
+// Tags returns the distinct additional tag keys used in the TimerList, sorted alphabetically.
+func (timerlist *TimerList) Tags() []string {
+	return timerlist.distinct(func(t Timer) []string {
+		keys := make([]string, 0, len(t.AdditionalTags))
+		for k := range t.AdditionalTags {
+			keys = append(keys, k)
+		}
+		return keys
+	})
+}
+
+// distinct returns the sorted, de-duplicated values returned by values for every Timer in the TimerList.
+func (timerlist *TimerList) distinct(values func(Timer) []string) []string {
+	seen := make(map[string]bool)
+	ret := []string{}
+	for _, t := range *timerlist {
+		for _, v := range values(t) {
+			if !seen[v] {
+				seen[v] = true
+				ret = append(ret, v)
+			}
 		}
 	}
-	return &t
+	sort.Strings(ret)
+	return ret
 }
 
-// String returns a complete list of timers in timer.txt format.
-func (timerlist *TimerList) String() string {
-	var ret string
-	for _, timer := range *timerlist {
-		ret += fmt.Sprintf("%s\n", timer.String())
+// InvalidTimers returns the timers that finish before they start, see *Timer.HasValidInterval().
+func (timerlist *TimerList) InvalidTimers() *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		return !t.HasValidInterval()
+	})
+}
+
+// TotalDuration returns the sum of the durations of all timers in the list.
+// Unfinished timers count up to time.Now(), see *Timer.Duration().
+func (timerlist *TimerList) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, t := range *timerlist {
+		total += t.Duration()
+	}
+	return total
+}
+
+// TotalFinishedDuration returns the sum of the durations of the finished timers in the list.
+// Unlike TotalDuration, the result doesn't change with the time it's called, see *Timer.FinishedDuration().
+func (timerlist *TimerList) TotalFinishedDuration() time.Duration {
+	var total time.Duration
+	for _, t := range *timerlist {
+		total += t.FinishedDuration()
+	}
+	return total
+}
+
+// TotalRoundedDuration returns the sum of the durations of all timers, each rounded up to a
+// multiple of increment before summing, see *Timer.RoundedDuration().
+// This can be more than TotalDuration() rounded up, as every timer is rounded separately.
+func (timerlist *TimerList) TotalRoundedDuration(increment time.Duration) time.Duration {
+	var total time.Duration
+	for _, t := range *timerlist {
+		total += t.RoundedDuration(increment)
+	}
+	return total
+}
+
+// DurationByContext returns the summed durations of the timers, keyed by context.
+// A timer with several contexts counts towards each of them.
+func (timerlist *TimerList) DurationByContext() map[string]time.Duration {
+	ret := make(map[string]time.Duration)
+	for _, t := range *timerlist {
+		dur := t.Duration()
+		for _, c := range t.Contexts {
+			ret[c] += dur
+		}
 	}
 	return ret
 }
 
+// DurationByProject returns the summed durations of the timers, keyed by project.
+// A timer with several projects counts towards each of them.
+func (timerlist *TimerList) DurationByProject() map[string]time.Duration {
+	ret := make(map[string]time.Duration)
+	for _, t := range *timerlist {
+		dur := t.Duration()
+		for _, p := range t.Projects {
+			ret[p] += dur
+		}
+	}
+	return ret
+}
+
+// TimerStats holds aggregate counts and durations for a TimerList.
+type TimerStats struct {
+	TotalCount    int
+	ActiveCount   int
+	FinishedCount int
+	TotalDuration time.Duration
+	ContextCounts map[string]int // Number of timers per context
+	ProjectCounts map[string]int // Number of timers per project
+}
+
+// Stats returns aggregate counts and durations for the TimerList.
+// Active and finished timers are counted the same way as GetActiveTimers and GetFinishedTimers.
+func (timerlist *TimerList) Stats() TimerStats {
+	stats := TimerStats{
+		ContextCounts: make(map[string]int),
+		ProjectCounts: make(map[string]int),
+	}
+	for _, t := range *timerlist {
+		stats.TotalCount++
+		if t.IsActive() {
+			stats.ActiveCount++
+		} else if !t.FinishDate.IsZero() {
+			stats.FinishedCount++
+		}
+		stats.TotalDuration += t.Duration()
+		for _, c := range t.Contexts {
+			stats.ContextCounts[c]++
+		}
+		for _, p := range t.Projects {
+			stats.ProjectCounts[p]++
+		}
+	}
+	return stats
+}
+
+// Len returns the number of timers in the TimerList.
+func (timerlist *TimerList) Len() int {
+	return len(*timerlist)
+}
+
+// Clear removes all timers from the TimerList, keeping its capacity so it can be reused.
+func (timerlist *TimerList) Clear() {
+	*timerlist = (*timerlist)[:0]
+}
+
+// String returns a complete list of timers in timer.txt format.
+func (timerlist *TimerList) String() string {
+	var b strings.Builder
+	timerlist.WriteTo(&b)
+	return b.String()
+}
+
 // AddTimer prepends a Timer to the current TimerList and takes care to set the Timer.Id correctly
+// The new Timer gets Id 1 and every other Timer is renumbered, see AppendTimer to keep Ids stable.
+// A Timer without a Layout or Precision gets those of the list, see SetDateLayout and SetDatePrecision.
 func (timerlist *TimerList) AddTimer(timer *Timer) {
+	timerlist.inheritFormat(timer)
 	// The new timer is going to be id 1
 	timer.Id = 1
-	for _, t := range *timerlist {
+	for i := range *timerlist {
 		// Everything else gets incremented
-		t.Id++
+		(*timerlist)[i].Id++
 	}
 	// Now prepend the timer to the slice
 	*timerlist = append(*timerlist, Timer{})
@@ -77,6 +341,108 @@ func (timerlist *TimerList) AddTimer(timer *Timer) {
 	(*timerlist)[0] = *timer
 }
 
+// AppendTimer appends a Timer to the current TimerList, setting Timer.Id to NextId().
+// Unlike AddTimer, the Ids of the other timers are left unchanged.
+// A Timer without a Layout or Precision gets those of the list, see SetDateLayout and SetDatePrecision.
+func (timerlist *TimerList) AppendTimer(timer *Timer) {
+	timerlist.inheritFormat(timer)
+	timer.Id = timerlist.NextId()
+	*timerlist = append(*timerlist, *timer)
+}
+
+// inheritFormat sets the Layout, Precision and Newline of a timer being added to the list to those of the first Timer in
+// the list, unless the timer has its own, so the list keeps writing dates and line endings the same way.
+func (timerlist *TimerList) inheritFormat(timer *Timer) {
+	if len(*timerlist) == 0 {
+		return
+	}
+	if timer.Layout == "" {
+		timer.Layout = (*timerlist)[0].Layout
+	}
+	if timer.Precision == 0 {
+		timer.Precision = (*timerlist)[0].Precision
+	}
+	if timer.Newline == "" {
+		timer.Newline = (*timerlist)[0].Newline
+	}
+}
+
+// NextId returns the Id following the highest Id in the TimerList.
+func (timerlist *TimerList) NextId() int {
+	maxId := 0
+	for _, t := range *timerlist {
+		if t.Id > maxId {
+			maxId = t.Id
+		}
+	}
+	return maxId + 1
+}
+
+// AppendList appends copies of the timers in other to the TimerList, assigning them new Ids like AppendTimer.
+func (timerlist *TimerList) AppendList(other TimerList) {
+	for _, t := range other {
+		t = t.Clone()
+		timerlist.AppendTimer(&t)
+	}
+}
+
+// Concat returns a new TimerList with the timers of both lists, renumbered with sequential Ids.
+// Duplicate timers are kept, see ConcatUnique to drop them.
+func (timerlist *TimerList) Concat(other TimerList) *TimerList {
+	return timerlist.concat(other, false)
+}
+
+// ConcatUnique returns a new TimerList with the timers of both lists, renumbered with sequential Ids.
+// Timers that are Equal to one already in the new list are left out.
+func (timerlist *TimerList) ConcatUnique(other TimerList) *TimerList {
+	return timerlist.concat(other, true)
+}
+
+func (timerlist *TimerList) concat(other TimerList, unique bool) *TimerList {
+	newList := make(TimerList, 0, len(*timerlist)+len(other))
+	for _, list := range []TimerList{*timerlist, other} {
+	timers:
+		for _, t := range list {
+			if unique {
+				for _, existing := range newList {
+					if existing.Equal(t) {
+						continue timers
+					}
+				}
+			}
+			t = t.Clone()
+			t.Id = len(newList) + 1
+			newList = append(newList, t)
+		}
+	}
+	return &newList
+}
+
+// Diff compares two versions of a TimerList, matching timers with *Timer.Equal.
+// Timers only in newList are added, timers only in oldList are removed, and timers in both are unchanged.
+// A modified timer shows up as removed in its old form and added in its new form.
+// The returned timers are copies, with the Ids they have in newList, or oldList for removed timers.
+func Diff(oldList, newList TimerList) (added, removed, unchanged TimerList) {
+	matched := make([]bool, len(oldList))
+timers:
+	for _, t := range newList {
+		for i, o := range oldList {
+			if !matched[i] && o.Equal(t) {
+				matched[i] = true
+				unchanged = append(unchanged, t.Clone())
+				continue timers
+			}
+		}
+		added = append(added, t.Clone())
+	}
+	for i, o := range oldList {
+		if !matched[i] {
+			removed = append(removed, o.Clone())
+		}
+	}
+	return added, removed, unchanged
+}
+
 // GetTimer returns the Timer with the given timer 'id' from the TimerList.
 // Returns an error if Timer could not be found.
 func (timerlist *TimerList) GetTimer(id int) (*Timer, error) {
@@ -88,6 +454,78 @@ func (timerlist *TimerList) GetTimer(id int) (*Timer, error) {
 	return nil, errors.New("timer not found")
 }
 
+// GetTimerByTagId returns the first Timer with the given 'id:' tag value from the TimerList, e.g. "abc123" for
+// ids that LoadOptions.IdFromTag can't use as Timer.Id.
+// Returns an error if Timer could not be found.
+func (timerlist *TimerList) GetTimerByTagId(id string) (*Timer, error) {
+	for i := range *timerlist {
+		if ([]Timer(*timerlist))[i].HasTagValue(idTagKey, id) {
+			return &([]Timer(*timerlist))[i], nil
+		}
+	}
+	return nil, errors.New("timer not found")
+}
+
+// GetLongestTimer returns the Timer with the longest Duration from the TimerList.
+// Unfinished timers are measured up to time.Now(), like *Timer.Duration().
+// Returns an error if the TimerList is empty.
+func (timerlist *TimerList) GetLongestTimer() (*Timer, error) {
+	return timerlist.getTimerByDuration(func(d1, d2 time.Duration) bool {
+		return d1 > d2
+	})
+}
+
+// GetShortestTimer returns the Timer with the shortest Duration from the TimerList.
+// Unfinished timers are measured up to time.Now(), like *Timer.Duration().
+// Returns an error if the TimerList is empty.
+func (timerlist *TimerList) GetShortestTimer() (*Timer, error) {
+	return timerlist.getTimerByDuration(func(d1, d2 time.Duration) bool {
+		return d1 < d2
+	})
+}
+
+// getTimerByDuration returns the first Timer whose duration is 'better' than all others.
+func (timerlist *TimerList) getTimerByDuration(better func(d1, d2 time.Duration) bool) (*Timer, error) {
+	if len(*timerlist) == 0 {
+		return nil, errors.New("timer list is empty")
+	}
+	now := time.Now()
+	best := 0
+	bestDur := (*timerlist)[0].DurationAsOf(now)
+	for i := range *timerlist {
+		if dur := (*timerlist)[i].DurationAsOf(now); better(dur, bestDur) {
+			best, bestDur = i, dur
+		}
+	}
+	return &(*timerlist)[best], nil
+}
+
+// OverlappingPairs returns the Ids of every pair of timers whose intervals overlap, see *Timer.Overlaps().
+func (timerlist *TimerList) OverlappingPairs() [][2]int {
+	var pairs [][2]int
+	now := time.Now()
+	for i, t1 := range *timerlist {
+		for _, t2 := range (*timerlist)[i+1:] {
+			if t1.overlapsAsOf(t2, now) {
+				pairs = append(pairs, [2]int{t1.Id, t2.Id})
+			}
+		}
+	}
+	return pairs
+}
+
+// UpdateTimer replaces the Timer with the given timer 'id' in the TimerList, keeping its Id.
+// Returns an error if Timer could not be found.
+func (timerlist *TimerList) UpdateTimer(id int, timer Timer) error {
+	t, err := timerlist.GetTimer(id)
+	if err != nil {
+		return err
+	}
+	timer.Id = id
+	*t = timer
+	return nil
+}
+
 // RemoveTimerById removes any Timer with given Timer 'id' from the TimerList.
 // Returns an error if no Timer was removed.
 func (timerlist *TimerList) RemoveTimerById(id int) error {
@@ -107,13 +545,13 @@ func (timerlist *TimerList) RemoveTimerById(id int) error {
 	return nil
 }
 
-// RemoveTimer removes any Timer from the TimerList with the same String representation as the given Timer.
+// RemoveTimer removes any Timer from the TimerList that is Equal to the given Timer.
 // Returns an error if no Timer was removed.
 func (timerlist *TimerList) RemoveTimer(timer Timer) error {
 	var newList TimerList
 	found := false
 	for _, t := range *timerlist {
-		if t.String() != timer.String() {
+		if !t.Equal(timer) {
 			newList = append(newList, t)
 		} else {
 			found = true
@@ -126,65 +564,584 @@ func (timerlist *TimerList) RemoveTimer(timer Timer) error {
 	return nil
 }
 
+// RemoveTimers removes every Timer from the TimerList that matches the given predicate.
+// Returns the number of timers removed. The remaining timers keep their Ids.
+func (timerlist *TimerList) RemoveTimers(predicate func(Timer) bool) int {
+	var newList TimerList
+	removed := 0
+	for _, t := range *timerlist {
+		if predicate(t) {
+			removed++
+		} else {
+			newList = append(newList, t)
+		}
+	}
+	*timerlist = newList
+	return removed
+}
+
+// ArchivePlan partitions the TimerList into the timers that would remain and the timers that would be
+// archived for the given predicate, without touching any file. The TimerList itself is not modified.
+func (timerlist *TimerList) ArchivePlan(predicate func(Timer) bool) (remaining TimerList, archived TimerList) {
+	for _, t := range *timerlist {
+		if predicate(t) {
+			archived = append(archived, t.Clone())
+		} else {
+			remaining = append(remaining, t.Clone())
+		}
+	}
+	return remaining, archived
+}
+
 // ArchiveTimerToFile removes the timer from the active list and concatenates it to
 // the passed in filename
 // Return an err if any part of that fails
+// The timer is only removed from the list once it has been written to the file.
+// If the list holds several timers Equal to timer, only the first one is archived and removed.
 func (timerlist *TimerList) ArchiveTimerToFile(timer Timer, filename string) error {
-	if err := timerlist.RemoveTimer(timer); err != nil {
+	idx := -1
+	for i, t := range *timerlist {
+		if t.Equal(timer) {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return errors.New("timer not found")
+	}
+	if err := AppendTimerToFilename(timer, filename); err != nil {
 		return err
 	}
-	f, err := os.OpenFile(filename, os.O_APPEND|os.O_WRONLY, 0600)
+	*timerlist = append((*timerlist)[:idx], (*timerlist)[idx+1:]...)
+	return nil
+}
+
+// ArchiveTimersToFile removes every timer matching the predicate from the list and appends them to the passed
+// in filename, creating it if needed. Returns the number of archived timers.
+// The timers are only removed from the list once they have all been written to the file.
+func (timerlist *TimerList) ArchiveTimersToFile(predicate func(Timer) bool, filename string) (int, error) {
+	remaining, archived := timerlist.ArchivePlan(predicate)
+	if len(archived) == 0 {
+		return 0, nil
+	}
+	f, err := openForAppend(filename)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	defer f.Close()
-	_, err = f.WriteString(timer.String() + "\n")
-	return err
+	if _, err = archived.WriteTo(f); err != nil {
+		return 0, err
+	}
+	if err = f.Close(); err != nil {
+		return 0, err
+	}
+	*timerlist = remaining
+	return len(archived), nil
+}
+
+// Clone returns a deep copy of the TimerList, see *Timer.Clone().
+func (timerlist *TimerList) Clone() *TimerList {
+	newList := make(TimerList, 0, len(*timerlist))
+	for _, t := range *timerlist {
+		newList = append(newList, t.Clone())
+	}
+	return &newList
+}
+
+// InLocation returns a copy of the TimerList with all dates converted to the given location, see *Timer.InLocation().
+func (timerlist *TimerList) InLocation(loc *time.Location) *TimerList {
+	newList := make(TimerList, 0, len(*timerlist))
+	for _, t := range *timerlist {
+		newList = append(newList, t.InLocation(loc))
+	}
+	return &newList
 }
 
 // Filter filters the current TimerList for the given predicate (a function that takes a timer as input and returns a
-// bool), and returns a new TimerList. The original TimerList is not modified.
+// bool), and returns a new TimerList. The original TimerList is not modified, and the returned timers are
+// clones that are safe to modify.
 func (timerlist *TimerList) Filter(predicate func(Timer) bool) *TimerList {
 	var newList TimerList
 	for _, t := range *timerlist {
 		if predicate(t) {
-			newList = append(newList, t)
+			newList = append(newList, t.Clone())
 		}
 	}
 	return &newList
 }
 
-// LoadFromFile loads a TimerList from *os.File.
-// Note: This will clear the current TimerList and overwrite it's contents with whatever is in *os.File.
-func (timerlist *TimerList) LoadFromFile(file *os.File) error {
+// Search returns a new TimerList with the timers whose Notes contain query, ignoring case.
+// If matchTags is true, timers with a Context or Project containing query also match.
+func (timerlist *TimerList) Search(query string, matchTags bool) *TimerList {
+	query = strings.ToLower(query)
+	return timerlist.search(func(s string) bool {
+		return strings.Contains(strings.ToLower(s), query)
+	}, matchTags)
+}
+
+// SearchRegex returns a new TimerList with the timers whose Notes match re.
+// If matchTags is true, timers with a Context or Project matching re also match.
+func (timerlist *TimerList) SearchRegex(re *regexp.Regexp, matchTags bool) *TimerList {
+	return timerlist.search(re.MatchString, matchTags)
+}
+
+func (timerlist *TimerList) search(match func(string) bool, matchTags bool) *TimerList {
+	return timerlist.Filter(func(t Timer) bool {
+		if match(t.Notes) {
+			return true
+		}
+		if !matchTags {
+			return false
+		}
+		for _, v := range append(append([]string{}, t.Contexts...), t.Projects...) {
+			if match(v) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// Page returns a copy of at most 'limit' timers, starting at index 'offset'.
+// Out of range offsets and limits are clamped, so an offset past the end or a limit of zero
+// returns an empty TimerList.
+func (timerlist *TimerList) Page(offset, limit int) *TimerList {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(*timerlist) {
+		offset = len(*timerlist)
+	}
+	if limit < 0 {
+		limit = 0
+	}
+	if limit > len(*timerlist)-offset {
+		limit = len(*timerlist) - offset
+	}
+	page := (*timerlist)[offset : offset+limit]
+	return page.Clone()
+}
+
+// Chunk splits a copy of the TimerList into pages of 'size' timers, the last one possibly smaller.
+// Returns nil if size is not positive.
+func (timerlist *TimerList) Chunk(size int) []*TimerList {
+	if size <= 0 {
+		return nil
+	}
+	var chunks []*TimerList
+	for offset := 0; offset < len(*timerlist); offset += size {
+		chunks = append(chunks, timerlist.Page(offset, size))
+	}
+	return chunks
+}
+
+// ForEach calls fn with a pointer to every Timer in the TimerList, so changes are kept.
+func (timerlist *TimerList) ForEach(fn func(*Timer)) {
+	for i := range *timerlist {
+		fn(&(*timerlist)[i])
+	}
+}
+
+// RenameProject renames the project 'from' to 'to' on every Timer in the TimerList.
+// Timers that already have the new project don't get it twice. Returns the number of timers changed.
+func (timerlist *TimerList) RenameProject(from, to string) int {
+	if from == to {
+		return 0
+	}
+	changed := 0
+	timerlist.ForEach(func(t *Timer) {
+		if t.RemoveProject(from) {
+			t.AddProject(to)
+			changed++
+		}
+	})
+	return changed
+}
+
+// RenameContext renames the context 'from' to 'to' on every Timer in the TimerList.
+// Timers that already have the new context don't get it twice. Returns the number of timers changed.
+func (timerlist *TimerList) RenameContext(from, to string) int {
+	if from == to {
+		return 0
+	}
+	changed := 0
+	timerlist.ForEach(func(t *Timer) {
+		if t.RemoveContext(from) {
+			t.AddContext(to)
+			changed++
+		}
+	})
+	return changed
+}
+
+// FilterIndices returns the indices of the timers in the TimerList that match the given predicate,
+// so the matching timers can be modified in place through (*timerlist)[i].
+func (timerlist *TimerList) FilterIndices(predicate func(Timer) bool) []int {
+	var indices []int
+	for i, t := range *timerlist {
+		if predicate(t) {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// MergeOverlapping returns a new TimerList where timers for the same task that overlap, or are separated by
+// at most 'gap', are merged into one timer spanning from the earliest start to the latest finish.
+// Timers are considered the same task if they have the same Notes, Contexts and Projects.
+// A merged timer is unfinished if any of its parts is, and keeps the Id of its earliest part.
+// The returned list is ordered by StartDate. The original TimerList is not modified.
+func (timerlist *TimerList) MergeOverlapping(gap time.Duration) *TimerList {
+	sorted := timerlist.Clone()
+	sorted.sortBy(func(t1, t2 *Timer) bool {
+		return sortByDate(true, t1.StartDate, t2.StartDate)
+	})
+	var newList TimerList
+	last := make(map[string]int) // Index in newList of the latest timer for each task
+	for _, t := range *sorted {
+		key := mergeKey(t)
+		if i, ok := last[key]; ok {
+			m := &newList[i]
+			if m.FinishDate.IsZero() {
+				// Still running, so anything starting later overlaps
+				continue
+			}
+			if !t.StartDate.After(m.FinishDate.Add(gap)) {
+				// The merged timer no longer matches the text of its first part
+				m.Original = ""
+				if t.FinishDate.IsZero() {
+					m.FinishDate = time.Time{}
+					m.Finished = false
+				} else if t.FinishDate.After(m.FinishDate) {
+					m.FinishDate = t.FinishDate
+				}
+				for k, v := range t.AdditionalTags {
+					if m.AdditionalTags == nil {
+						m.AdditionalTags = make(map[string]string)
+					}
+					if !m.HasTag(k) {
+						m.AdditionalTags[k] = v
+					}
+				}
+				continue
+			}
+		}
+		last[key] = len(newList)
+		newList = append(newList, t)
+	}
+	return &newList
+}
+
+// SplitAtMidnight returns a new TimerList where every timer that crosses midnight, in the timezone of its
+// StartDate, is split into one timer per calendar day. All but the last part are finished at midnight,
+// the last part keeps the FinishDate of the original timer. Unfinished timers are split up to time.Now().
+// The parts keep the Id of the timer they were split from. The original TimerList is not modified.
+func (timerlist *TimerList) SplitAtMidnight() *TimerList {
+	var newList TimerList
+	now := time.Now()
+	for _, t := range *timerlist {
+		end := t.FinishDate
+		if end.IsZero() {
+			end = now
+		}
+		start := t.StartDate
+		for {
+			y, m, d := start.Date()
+			midnight := time.Date(y, m, d+1, 0, 0, 0, 0, start.Location())
+			if !midnight.Before(end) {
+				break
+			}
+			part := t.Clone()
+			part.Original = ""
+			part.StartDate = start
+			part.FinishDate = midnight
+			part.Finished = true
+			newList = append(newList, part)
+			start = midnight
+		}
+		part := t.Clone()
+		if !start.Equal(t.StartDate) {
+			part.Original = ""
+		}
+		part.StartDate = start
+		newList = append(newList, part)
+	}
+	return &newList
+}
+
+// dayLayout formats the keys of GroupByDay.
+const dayLayout = "2006-01-02"
+
+// GroupByDay returns copies of the timers grouped by the local day they started on, keyed as "2006-01-02".
+// To have timers that span several days counted on each of them, use SplitAtMidnight first on the list
+// converted with InLocation(time.Local), as SplitAtMidnight splits in each timer's own time zone.
+func (timerlist *TimerList) GroupByDay() map[string]*TimerList {
+	days := make(map[string]*TimerList)
+	for _, t := range *timerlist {
+		day := t.StartDate.Local().Format(dayLayout)
+		if days[day] == nil {
+			days[day] = &TimerList{}
+		}
+		*days[day] = append(*days[day], t.Clone())
+	}
+	return days
+}
+
+// SortedDays returns the keys of GroupByDay in ascending order.
+func (timerlist *TimerList) SortedDays() []string {
+	var days []string
+	for day := range timerlist.GroupByDay() {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	return days
+}
+
+// mergeKey identifies the task a timer belongs to for MergeOverlapping.
+func mergeKey(t Timer) string {
+	contexts := strings.Join(sortedStrings(t.Contexts), " ")
+	projects := strings.Join(sortedStrings(t.Projects), " ")
+	return t.Notes + "\x00" + contexts + "\x00" + projects
+}
+
+// LoadOptions controls how LoadFromWithOptions reads a TimerList.
+type LoadOptions struct {
+	DateLayout string // Layout tried first when parsing dates, and kept for String(). DateLayout is used when empty
+	Lenient    bool   // Skip lines that fail to parse instead of stopping at the first one
+	Strict     bool   // Fail on repeated tag keys and malformed tokens, see ParseTimerStrict
+	KeepIndent bool   // Keep the leading whitespace of each line in Timer.Indent, so String() writes it back
+	// Keep '#' comment lines in Timer.Comments of the timer below them, so WriteTo writes them back.
+	// Comments after the last timer are kept in its Timer.TrailingComments, which WriteTo writes at the end of the
+	// file even after the timers are sorted or added to. Comment lines are never parsed as timers, and are dropped
+	// if the file has no timers at all.
+	KeepComments bool
+	// Use the value of a positive integer 'id:' tag as Timer.Id instead of the line position. The tag is kept,
+	// so it is written back. Only numeric ids are supported as Timer.Id: other values, like 'id:abc123', and
+	// repeats of an id already used are ignored, those timers get ids following the highest tagged id, in file
+	// order. Use GetTimerByTagId to find a timer by any 'id:' tag value.
+	IdFromTag bool
+}
+
+// LoadFrom loads a TimerList from an io.Reader.
+// The line ending of every timer is kept in Timer.Newline, so a file with CRLF line endings is written back with them.
+// Note: This will clear the current TimerList and overwrite it's contents with whatever is read from r.
+func (timerlist *TimerList) LoadFrom(r io.Reader) error {
+	return timerlist.LoadFromWithOptions(r, LoadOptions{})
+}
+
+// LoadFromLenient loads a TimerList from an io.Reader, skipping any lines that fail to parse.
+// Returns the errors for the skipped lines. Only successfully parsed timers are assigned an id.
+// Note: This will clear the current TimerList and overwrite it's contents with whatever is read from r.
+func (timerlist *TimerList) LoadFromLenient(r io.Reader) []error {
+	return timerlist.loadFrom(context.Background(), r, LoadOptions{Lenient: true})
+}
+
+// LoadFromWithOptions loads a TimerList from an io.Reader, as configured by opts.
+// In lenient mode the errors for all skipped lines are joined into the returned error.
+// Note: This will clear the current TimerList and overwrite it's contents with whatever is read from r.
+func (timerlist *TimerList) LoadFromWithOptions(r io.Reader, opts LoadOptions) error {
+	return errors.Join(timerlist.loadFrom(context.Background(), r, opts)...)
+}
+
+// LoadFromContext loads a TimerList from an io.Reader, like LoadFrom, but stops when ctx is done.
+// If loading is cancelled, the TimerList is left empty and ctx.Err() is returned.
+// Note: This will clear the current TimerList and overwrite it's contents with whatever is read from r.
+func (timerlist *TimerList) LoadFromContext(ctx context.Context, r io.Reader) error {
+	if errs := timerlist.loadFrom(ctx, r, LoadOptions{}); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// loadFrom reads timers from r into the TimerList.
+// Unless opts.Lenient is set, loading stops at the first error. Loading always stops when ctx is done.
+func (timerlist *TimerList) loadFrom(ctx context.Context, r io.Reader, opts LoadOptions) []error {
+	var errs []error
 	*timerlist = []Timer{} // Empty timerlist
 	timerId := 1
-	scanner := bufio.NewScanner(file)
+	lineNum := 0
+	var comments []string
+	var crlf bool // Whether the last scanned line ended in CRLF
+	scanner := bufio.NewScanner(r)
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		// ScanLines drops both the '\n' and a '\r' before it
+		crlf = token != nil && advance == len(token)+2
+		return advance, token, err
+	})
 	for scanner.Scan() {
-		text := strings.Trim(scanner.Text(), "\t\n\r") // Read Line
+		if err := ctx.Err(); err != nil {
+			*timerlist = []Timer{}
+			return []error{err}
+		}
+		lineNum++
+		line := scanner.Text()
+		text := strings.Trim(line, "\t\n\r") // Read Line
 		// Ignore blank lines
-		if text == "" {
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		// Skip comment lines
+		if strings.HasPrefix(strings.TrimSpace(text), "#") {
+			if opts.KeepComments {
+				comments = append(comments, line)
+			}
 			continue
 		}
-		timer, err := ParseTimer(text)
+		timer, err := parseTimer(text, opts)
 		if err != nil {
-			return err
+			errs = append(errs, fmt.Errorf("line %d %q: %w", lineNum, text, err))
+			if !opts.Lenient {
+				return errs
+			}
+			continue
 		}
 		timer.Id = timerId
+		if opts.KeepIndent {
+			timer.Indent = line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		}
+		timer.Comments, comments = comments, nil
+		if crlf {
+			timer.Newline = CRLF
+		}
 		*timerlist = append(*timerlist, *timer)
 		timerId++
 	}
+	if n := len(*timerlist); n > 0 && len(comments) > 0 {
+		(*timerlist)[n-1].TrailingComments = comments
+	}
+	if opts.IdFromTag {
+		timerlist.setIdsFromTags()
+	}
 	if err := scanner.Err(); err != nil {
-		return err
+		errs = append(errs, err)
 	}
-	return nil
+	return errs
+}
+
+// setIdsFromTags sets the Id of every timer with a positive integer 'id:' tag to its value, see
+// LoadOptions.IdFromTag. The other timers are numbered after the highest tagged id, so no ids repeat.
+func (timerlist *TimerList) setIdsFromTags() {
+	tagged := make(map[int]bool)
+	hasTag := make([]bool, len(*timerlist))
+	maxId := 0
+	for i, t := range *timerlist {
+		if id, err := strconv.Atoi(t.AdditionalTags[idTagKey]); err == nil && id > 0 && !tagged[id] {
+			tagged[id] = true
+			hasTag[i] = true
+			(*timerlist)[i].Id = id
+			if id > maxId {
+				maxId = id
+			}
+		}
+	}
+	for i := range *timerlist {
+		if !hasTag[i] {
+			maxId++
+			(*timerlist)[i].Id = maxId
+		}
+	}
+}
+
+// SetDatePrecision sets the precision that String() truncates dates to on every Timer in the TimerList,
+// e.g. time.Second to never write fractional seconds with a layout like time.RFC3339Nano. Zero keeps dates as is.
+// Timers added later with AddTimer or AppendTimer get the same precision, unless they have their own.
+func (timerlist *TimerList) SetDatePrecision(precision time.Duration) {
+	for i := range *timerlist {
+		(*timerlist)[i].Precision = precision
+	}
+}
+
+// SetDateLayout sets the date layout used by String() on every Timer in the TimerList.
+// This allows lists to use different layouts without changing the package wide DateLayout.
+// Timers added later with AddTimer or AppendTimer get the same layout, unless they have their own.
+func (timerlist *TimerList) SetDateLayout(layout string) {
+	for i := range *timerlist {
+		(*timerlist)[i].Layout = layout
+	}
+}
+
+// LoadFromFile loads a TimerList from *os.File.
+// Note: This will clear the current TimerList and overwrite it's contents with whatever is in *os.File.
+func (timerlist *TimerList) LoadFromFile(file *os.File) error {
+	return timerlist.LoadFrom(file)
+}
+
+// Line endings for WriteOptions.Newline
+const (
+	LF   = "\n"
+	CRLF = "\r\n"
+)
+
+// WriteOptions controls how WriteToWithOptions and WriteToFilenameWithOptions write a TimerList.
+type WriteOptions struct {
+	// Line ending written after every timer, overriding the Timer.Newline read by LoadFrom. When both are empty
+	// LF is used. Use CRLF to write Windows line endings
+	Newline  string
+	MkdirAll bool // Create missing parent directories, with mode 0755, when writing to a file
+}
+
+// WriteTo writes a TimerList to an io.Writer in timer.txt format, one timer at a time.
+// Returns the number of bytes written, implementing io.WriterTo.
+func (timerlist *TimerList) WriteTo(w io.Writer) (int64, error) {
+	return timerlist.WriteToWithOptions(w, WriteOptions{})
+}
+
+// WriteToWithOptions writes a TimerList to an io.Writer in timer.txt format, as configured by opts.
+// The Comments of each timer are written above it, and all Timer.TrailingComments after the last timer.
+// Returns the number of bytes written.
+func (timerlist *TimerList) WriteToWithOptions(w io.Writer, opts WriteOptions) (int64, error) {
+	cw := &countingWriter{w: w}
+	writer := bufio.NewWriter(cw)
+	var trailing []string
+	for _, timer := range *timerlist {
+		newline := opts.Newline
+		if newline == "" {
+			newline = timer.Newline
+		}
+		if newline == "" {
+			newline = LF
+		}
+		for _, comment := range timer.Comments {
+			if _, err := writer.WriteString(comment + newline); err != nil {
+				return cw.n, err
+			}
+		}
+		if _, err := writer.WriteString(timer.String()); err != nil {
+			return cw.n, err
+		}
+		if _, err := writer.WriteString(newline); err != nil {
+			return cw.n, err
+		}
+		for _, comment := range timer.TrailingComments {
+			trailing = append(trailing, comment+newline)
+		}
+	}
+	// End of file comments stay at the end, whichever timer carries them
+	for _, line := range trailing {
+		if _, err := writer.WriteString(line); err != nil {
+			return cw.n, err
+		}
+	}
+	err := writer.Flush()
+	return cw.n, err
+}
+
+// countingWriter counts the bytes written to the underlying io.Writer.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
 }
 
 // WriteToFile writes a TimerList to *os.File
 func (timerlist *TimerList) WriteToFile(file *os.File) error {
-	writer := bufio.NewWriter(file)
-	_, err := writer.WriteString(timerlist.String())
-	writer.Flush()
+	_, err := timerlist.WriteTo(file)
 	return err
 }
 
@@ -199,8 +1156,113 @@ func (timerlist *TimerList) LoadFromFilename(filename string) error {
 }
 
 // WriteToFilename writes a TimerList to the specified file (most likely called "timer.txt").
+// The list is written to a temporary file in the same directory, which then replaces the file,
+// so the file is never left half written. The mode of an existing file is kept.
+// If the file is a symlink, the file it points to is replaced and the symlink is kept.
 func (timerlist *TimerList) WriteToFilename(filename string) error {
-	return ioutil.WriteFile(filename, []byte(timerlist.String()), 0640)
+	return timerlist.WriteToFilenameWithOptions(filename, WriteOptions{})
+}
+
+// WriteToFilenameMkdirAll writes a TimerList to the specified file like WriteToFilename,
+// creating any missing parent directories first.
+func (timerlist *TimerList) WriteToFilenameMkdirAll(filename string) error {
+	return timerlist.WriteToFilenameWithOptions(filename, WriteOptions{MkdirAll: true})
+}
+
+// WriteToFilenameWithOptions writes a TimerList to the specified file like WriteToFilename, as configured by opts.
+func (timerlist *TimerList) WriteToFilenameWithOptions(filename string, opts WriteOptions) error {
+	if opts.MkdirAll {
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			return err
+		}
+	}
+	// Replace the file a symlink points to, rather than the symlink itself
+	if target, err := filepath.EvalSymlinks(filename); err == nil {
+		filename = target
+	}
+	var mode os.FileMode = 0640
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	// Clean up the temporary file, unless it has been renamed
+	defer os.Remove(tmp.Name())
+	if _, err = timerlist.WriteToWithOptions(tmp, opts); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// LoadFromGzipFilename loads a TimerList from a gzip compressed file.
+// Note: This will clear the current TimerList and overwrite it's contents with whatever is in the file.
+func (timerlist *TimerList) LoadFromGzipFilename(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	return timerlist.LoadFrom(reader)
+}
+
+// WriteToGzipFilename writes a TimerList to the specified file, gzip compressed.
+func (timerlist *TimerList) WriteToGzipFilename(filename string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := gzip.NewWriter(file)
+	if _, err := timerlist.WriteTo(writer); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// WriteToFilenameSorted writes a sorted copy of the TimerList to the specified file, see Sort().
+// The TimerList itself is not reordered.
+func (timerlist *TimerList) WriteToFilenameSorted(filename string, sortFlag int) error {
+	sorted := timerlist.Clone()
+	if err := sorted.Sort(sortFlag); err != nil {
+		return err
+	}
+	return sorted.WriteToFilename(filename)
+}
+
+// ParseTimers parses a multi-line string, one timer per line, into a TimerList.
+// Blank lines are skipped and errors are reported the same way as LoadFromFile.
+func ParseTimers(text string) (TimerList, error) {
+	return LoadFrom(strings.NewReader(text))
+}
+
+// LoadFrom loads and returns a TimerList from an io.Reader.
+func LoadFrom(r io.Reader) (TimerList, error) {
+	timerlist := TimerList{}
+	if err := timerlist.LoadFrom(r); err != nil {
+		return nil, err
+	}
+	return timerlist, nil
 }
 
 // LoadFromFile loads and returns a TimerList from *os.File.
@@ -212,6 +1274,14 @@ func LoadFromFile(file *os.File) (TimerList, error) {
 	return timerlist, nil
 }
 
+// LoadFromFileLenient loads and returns a TimerList from *os.File, skipping any lines that fail to parse.
+// Returns the errors for the skipped lines alongside the timers that were loaded.
+func LoadFromFileLenient(file *os.File) (TimerList, []error) {
+	timerlist := TimerList{}
+	errs := timerlist.LoadFromLenient(file)
+	return timerlist, errs
+}
+
 // WriteToFile writes a TimerList to *os.File.
 func WriteToFile(timerlist *TimerList, file *os.File) error {
 	return timerlist.WriteToFile(file)
@@ -230,3 +1300,40 @@ func LoadFromFilename(filename string) (TimerList, error) {
 func WriteToFilename(timerlist *TimerList, filename string) error {
 	return timerlist.WriteToFilename(filename)
 }
+
+// AppendTimerToFilename appends a single Timer to the specified file, creating it if it doesn't exist.
+// Unlike WriteToFilename, the existing contents of the file are left untouched.
+func AppendTimerToFilename(timer Timer, filename string) error {
+	f, err := openForAppend(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(timer.String() + "\n")
+	return err
+}
+
+// openForAppend opens the specified file for appending, creating it if it doesn't exist.
+// If the file doesn't end in a newline, one is written first so appended timers start on a line of their own.
+func openForAppend(filename string) (*os.File, error) {
+	f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err = f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			_, err = f.WriteString("\n")
+		}
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
@@ -0,0 +1,1681 @@
+package timertxt
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// loadTestList loads a TimerList from timer.txt text, failing the test on errors.
+func loadTestList(t *testing.T, text string) TimerList {
+	t.Helper()
+	var timerlist TimerList
+	if err := timerlist.LoadFrom(strings.NewReader(text)); err != nil {
+		t.Fatal(err)
+	}
+	return timerlist
+}
+
+func TestArchiveTimerToNewFile(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done\n2019-02-15T11:43:00-06:00 Open\n")
+	filename := filepath.Join(t.TempDir(), "done.txt")
+	if err := timerlist.ArchiveTimerToFile(timerlist[0], filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 1 || timerlist[0].Notes != "Open" {
+		t.Errorf("Expected only the open timer to remain, got %v", timerlist)
+	}
+	archive, err := LoadFromFilename(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive) != 1 || archive[0].Notes != "Done" {
+		t.Errorf("Expected the archived timer in the new file, got %v", archive)
+	}
+}
+
+func TestArchiveTimerToFileFailureKeepsTimer(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done\n")
+	filename := filepath.Join(t.TempDir(), "missing", "done.txt")
+	if err := timerlist.ArchiveTimerToFile(timerlist[0], filename); err == nil {
+		t.Fatal("Expected an error archiving into a missing directory")
+	}
+	if len(timerlist) != 1 {
+		t.Errorf("Expected the timer to stay in the list, got %v", timerlist)
+	}
+}
+
+func TestArchiveTimerToFileDuplicates(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done\nx 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done\n")
+	filename := filepath.Join(t.TempDir(), "done.txt")
+	if err := timerlist.ArchiveTimerToFile(timerlist[0], filename); err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 1 || timerlist[0].Id != 2 {
+		t.Errorf("Expected only the first copy to be archived, got %v", timerlist)
+	}
+	archive, err := LoadFromFilename(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive) != 1 {
+		t.Errorf("Expected one archived timer, got %v", archive)
+	}
+}
+
+func TestGetTimersInRangeBoundaries(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T00:00:00Z 2019-02-15T01:00:00Z AtStart",
+		"x 2019-02-15T23:59:59Z 2019-02-16T01:00:00Z AtEnd",
+		"2019-02-14T10:00:00Z OpenBefore",
+		"x 2019-02-14T10:00:00Z 2019-02-14T11:00:00Z Before",
+	}, "\n"))
+	start := time.Date(2019, 2, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 2, 15, 23, 59, 59, 0, time.UTC)
+	got := timerlist.GetTimersInRange(start, end)
+	if len(*got) != 2 || (*got)[0].Notes != "AtStart" || (*got)[1].Notes != "AtEnd" {
+		t.Errorf("Expected AtStart and AtEnd, got %v", got)
+	}
+}
+
+func TestTimerListJSONRoundTrip(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done @home\n2019-02-15T11:43:00-06:00 Open +timertxt\n")
+	data, err := json.Marshal(timerlist)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded := TimerList{Timer{Notes: "Existing"}}
+	if err := loaded.LoadFromJSON(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 2 || loaded[0].Id != 1 || loaded[1].Id != 2 {
+		t.Fatalf("Expected two timers with sequential ids, got %v", loaded)
+	}
+	again, err := json.Marshal(loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, again) {
+		t.Errorf("Expected %s after round trip, got %s", data, again)
+	}
+}
+
+func TestEmptyTimerListJSON(t *testing.T) {
+	for _, timerlist := range []TimerList{nil, {}} {
+		data, err := json.Marshal(timerlist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "[]" {
+			t.Errorf("Expected [], got %s", data)
+		}
+	}
+}
+
+func TestDurationTotals(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z One @home @work +a",
+		"x 2019-02-15T08:00:00Z 2019-02-15T10:00:00Z Two @work +a +b",
+	}, "\n"))
+	active := NewTimer().WithNotes("Active").WithContext("home").WithStart(time.Now().Add(-time.Hour))
+	timerlist.AppendTimer(active)
+
+	if total := timerlist.TotalDuration(); total < 4*time.Hour || total > 4*time.Hour+time.Minute {
+		t.Errorf("Expected a total of about 4h, got %v", total)
+	}
+	byContext := timerlist.DurationByContext()
+	if d := byContext["work"]; d != 3*time.Hour {
+		t.Errorf("Expected 3h for @work, got %v", d)
+	}
+	if d := byContext["home"]; d < 2*time.Hour || d > 2*time.Hour+time.Minute {
+		t.Errorf("Expected about 2h for @home, got %v", d)
+	}
+	byProject := timerlist.DurationByProject()
+	if byProject["a"] != 3*time.Hour || byProject["b"] != 2*time.Hour {
+		t.Errorf("Expected 3h for +a and 2h for +b, got %v", byProject)
+	}
+}
+
+func TestLoadFromSkipsBlankLines(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T11:43:00-06:00 One\n\n   \n\t\n2019-02-15T12:43:00-06:00 Two\n")
+	if len(timerlist) != 2 || timerlist[1].Id != 2 {
+		t.Errorf("Expected two timers, got %v", timerlist)
+	}
+}
+
+func TestAppendTimerToFilename(t *testing.T) {
+	dir := t.TempDir()
+	timer, err := ParseTimer("2019-02-15T11:43:00-06:00 Appended")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	empty := filepath.Join(dir, "empty.txt")
+	if err := AppendTimerToFilename(*timer, empty); err != nil {
+		t.Fatal(err)
+	}
+	if timerlist, err := LoadFromFilename(empty); err != nil || len(timerlist) != 1 {
+		t.Errorf("Expected one timer, got %v (%v)", timerlist, err)
+	}
+
+	populated := filepath.Join(dir, "timer.txt")
+	if err := os.WriteFile(populated, []byte("2019-02-15T06:00:00-06:00 One\n2019-02-15T07:00:00-06:00 Two\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendTimerToFilename(*timer, populated); err != nil {
+		t.Fatal(err)
+	}
+	timerlist, err := LoadFromFilename(populated)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 3 || timerlist[2].Notes != "Appended" {
+		t.Errorf("Expected three timers ending with the appended one, got %v", timerlist)
+	}
+
+	noNewline := filepath.Join(dir, "nonewline.txt")
+	if err := os.WriteFile(noNewline, []byte("2019-02-15T06:00:00-06:00 One"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendTimerToFilename(*timer, noNewline); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(noNewline)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2019-02-15T06:00:00-06:00 One\n2019-02-15T11:43:00-06:00 Appended\n"; string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
+
+func TestLoadFromReader(t *testing.T) {
+	timerlist, err := LoadFrom(strings.NewReader("2019-02-15T06:00:00-06:00 One\n2019-02-15T07:00:00-06:00 Two @home\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 2 || timerlist[1].Id != 2 || !timerlist[1].HasContext("home") {
+		t.Errorf("Expected two timers, got %v", timerlist)
+	}
+}
+
+func TestWriteTo(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done\n2019-02-15T11:43:00-06:00 Open\n")
+	var buf bytes.Buffer
+	n, err := timerlist.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != timerlist.String() {
+		t.Errorf("Expected %q, got %q", timerlist.String(), buf.String())
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("Expected %d bytes written, got %d", buf.Len(), n)
+	}
+}
+
+func TestLoadFromErrorLineNumbers(t *testing.T) {
+	text := "2019-02-15T06:00:00-06:00 One\nnot a timer\n2019-02-15T07:00:00-06:00 Two\nx bad\n"
+	var timerlist TimerList
+	err := timerlist.LoadFrom(strings.NewReader(text))
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "not a timer") {
+		t.Errorf("Expected an error for line 2, got %v", err)
+	}
+	err = timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{Lenient: true})
+	if err == nil || !strings.Contains(err.Error(), "line 2") || !strings.Contains(err.Error(), "line 4") {
+		t.Errorf("Expected errors for lines 2 and 4, got %v", err)
+	}
+}
+
+func TestLoadFromFileLenient(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "timer.txt")
+	text := "2019-02-15T06:00:00-06:00 One\nbad line\n2019-02-15T07:00:00-06:00 Two\nalso bad\n2019-02-15T08:00:00-06:00 Three\n"
+	if err := os.WriteFile(filename, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	timerlist, errs := LoadFromFileLenient(f)
+	if len(timerlist) != 3 || timerlist[2].Notes != "Three" || timerlist[2].Id != 3 {
+		t.Errorf("Expected three timers with ids 1-3, got %v", timerlist)
+	}
+	if len(errs) != 2 || !strings.Contains(errs[0].Error(), "line 2") || !strings.Contains(errs[1].Error(), "line 4") {
+		t.Errorf("Expected errors for lines 2 and 4, got %v", errs)
+	}
+}
+
+// notesOf joins the Notes of every timer in the list, for comparing orderings.
+func notesOf(timerlist TimerList) string {
+	notes := make([]string, len(timerlist))
+	for i := range timerlist {
+		notes[i] = timerlist[i].Notes
+	}
+	return strings.Join(notes, " ")
+}
+
+func TestSortByPriority(t *testing.T) {
+	text := strings.Join([]string{
+		"2019-02-15T09:00:00Z None1",
+		"(B) 2019-02-15T08:00:00Z B1",
+		"(A) 2019-02-15T10:00:00Z A1",
+		"2019-02-15T07:00:00Z None2",
+		"(B) 2019-02-15T06:00:00Z B2",
+	}, "\n")
+	tests := []struct {
+		flag  int
+		notes string
+	}{
+		{SORT_PRIORITY_ASC, "A1 B2 B1 None2 None1"},
+		{SORT_PRIORITY_DESC, "None2 None1 B2 B1 A1"},
+	}
+	for _, tt := range tests {
+		timerlist := loadTestList(t, text)
+		if err := timerlist.Sort(tt.flag); err != nil {
+			t.Fatal(err)
+		}
+		if got := notesOf(timerlist); got != tt.notes {
+			t.Errorf("Sort(%d): Expected %q, got %q", tt.flag, tt.notes, got)
+		}
+	}
+}
+
+func TestSortTiesAreDeterministic(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T09:00:00Z First",
+		"2019-02-15T09:00:00Z Second",
+		"2019-02-15T08:00:00Z Earlier",
+		"2019-02-15T09:00:00Z Third",
+		"2019-02-15T09:00:00Z Fourth",
+	}, "\n"))
+	// None of the timers are finished, so FinishDate ties everywhere.
+	tests := []struct {
+		flag  int
+		notes string
+	}{
+		{SORT_START_DATE_ASC, "Earlier First Second Third Fourth"},
+		{SORT_START_DATE_DESC, "First Second Third Fourth Earlier"},
+		{SORT_FINISH_DATE_ASC, "Earlier First Second Third Fourth"},
+		{SORT_FINISH_DATE_DESC, "Earlier First Second Third Fourth"},
+	}
+	for i := 0; i < 20; i++ {
+		for _, tt := range tests {
+			rand.Shuffle(len(timerlist), func(i, j int) {
+				timerlist[i], timerlist[j] = timerlist[j], timerlist[i]
+			})
+			if err := timerlist.Sort(tt.flag); err != nil {
+				t.Fatal(err)
+			}
+			if got := notesOf(timerlist); got != tt.notes {
+				t.Fatalf("Sort(%d): Expected %q, got %q", tt.flag, tt.notes, got)
+			}
+		}
+	}
+}
+
+func TestGetTimersWithTag(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T08:00:00Z Report due:2019-02-15 billable:yes",
+		"2019-02-15T09:00:00Z Meeting billable:no",
+		"2019-02-15T10:00:00Z Lunch",
+		"2019-02-15T11:00:00Z Review due:2019-02-20",
+	}, "\n"))
+	if got := notesOf(*timerlist.GetTimersWithTag("billable")); got != "Report Meeting" {
+		t.Errorf("Expected Report and Meeting to be billable, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersWithTag("due")); got != "Report Review" {
+		t.Errorf("Expected Report and Review to be due, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersWithTagValue("due", "2019-02-15")); got != "Report" {
+		t.Errorf("Expected only Report due on 2019-02-15, got %q", got)
+	}
+	if got := timerlist.GetTimersWithTag("missing"); len(*got) != 0 {
+		t.Errorf("Expected no timers, got %v", got)
+	}
+	if !timerlist[1].HasTagValue("billable", "no") || timerlist[1].HasTagValue("billable", "yes") || timerlist[2].HasTag("billable") {
+		t.Error("Expected HasTag and HasTagValue to match the parsed tags")
+	}
+}
+
+func TestGetFinishedTimers(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1",
+		"2019-02-15T08:00:00Z Open1",
+		"x 2019-02-15T09:00:00Z 2019-02-15T10:00:00Z Done2",
+		"2019-02-15T11:00:00Z Open2",
+	}, "\n"))
+	if got := notesOf(*timerlist.GetFinishedTimers()); got != "Done1 Done2" {
+		t.Errorf("Expected Done1 and Done2 to be finished, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetActiveTimers()); got != "Open1 Open2" {
+		t.Errorf("Expected Open1 and Open2 to be active, got %q", got)
+	}
+}
+
+func TestRemoveTimers(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1 @home",
+		"2019-02-15T08:00:00Z Open1 @office",
+		"x 2019-02-15T09:00:00Z 2019-02-15T10:00:00Z Done2 @office",
+		"2019-02-15T11:00:00Z Open2 @home",
+	}, "\n"))
+	if n := timerlist.RemoveTimers(func(t Timer) bool { return t.Finished }); n != 2 {
+		t.Errorf("Expected 2 finished timers removed, got %d", n)
+	}
+	if got := notesOf(timerlist); got != "Open1 Open2" {
+		t.Errorf("Expected Open1 and Open2 to remain, got %q", got)
+	}
+	if timerlist[0].Id != 2 || timerlist[1].Id != 4 {
+		t.Errorf("Expected the remaining timers to keep their Ids, got %d and %d", timerlist[0].Id, timerlist[1].Id)
+	}
+	if n := timerlist.RemoveTimers(func(t Timer) bool { return t.HasContext("home") }); n != 1 {
+		t.Errorf("Expected 1 @home timer removed, got %d", n)
+	}
+	if got := notesOf(timerlist); got != "Open1" {
+		t.Errorf("Expected Open1 to remain, got %q", got)
+	}
+}
+
+func TestUpdateTimer(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n2019-02-15T10:00:00Z Third\n")
+	updated := timerlist[1]
+	updated.Notes = "Edited"
+	updated.Id = 42
+	if err := timerlist.UpdateTimer(2, updated); err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 3 {
+		t.Errorf("Expected 3 timers, got %d", len(timerlist))
+	}
+	if timerlist[1].Id != 2 || timerlist[1].Notes != "Edited" {
+		t.Errorf("Expected timer 2 to be edited, got %v (Id %d)", timerlist[1], timerlist[1].Id)
+	}
+	if err := timerlist.UpdateTimer(7, updated); err == nil {
+		t.Error("Expected an error updating a missing timer")
+	}
+}
+
+func TestParseTimers(t *testing.T) {
+	timerlist, err := ParseTimers("2019-02-15T08:00:00Z First\n\n2019-02-15T09:00:00Z Second\n2019-02-15T10:00:00Z Third")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notesOf(timerlist); got != "First Second Third" {
+		t.Errorf("Expected three timers, got %q", got)
+	}
+	for i, timer := range timerlist {
+		if timer.Id != i+1 {
+			t.Errorf("Expected Id %d, got %d", i+1, timer.Id)
+		}
+	}
+	if _, err := ParseTimers("2019-02-15T08:00:00Z First\nnot a timer"); err == nil {
+		t.Error("Expected an error for a malformed line")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00Z 2019-02-15T07:30:00Z Lunch, then coffee @home +food +fun\n2019-02-15T08:00:00Z Open\n")
+	var buf bytes.Buffer
+	if err := timerlist.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got %d records", len(records))
+	}
+	want := []string{"1", "2019-02-15T06:00:00Z", "2019-02-15T07:30:00Z", "1.50", "true", "Lunch, then coffee", "food fun", "home"}
+	if strings.Join(records[0], "|") != strings.Join(csvHeader, "|") {
+		t.Errorf("Expected header %v, got %v", csvHeader, records[0])
+	}
+	if strings.Join(records[1], "|") != strings.Join(want, "|") {
+		t.Errorf("Expected %v, got %v", want, records[1])
+	}
+	if len(records[2]) != len(csvHeader) || records[2][2] != "" || records[2][4] != "false" {
+		t.Errorf("Expected an unfinished row, got %v", records[2])
+	}
+}
+
+func TestCSVRoundTrip(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T07:30:00-06:00 Lunch, then coffee @home +food +fun\n2019-02-15T08:00:00Z Open @office\n")
+	var buf bytes.Buffer
+	if err := timerlist.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var loaded TimerList
+	if err := loaded.LoadCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.String() != timerlist.String() {
+		t.Errorf("Expected %q, got %q", timerlist.String(), loaded.String())
+	}
+}
+
+func TestLoadCSVColumnOrder(t *testing.T) {
+	var timerlist TimerList
+	err := timerlist.LoadCSV(strings.NewReader("Notes,Contexts,StartDate\nWork,home office,2019-02-15T08:00:00Z\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 1 || timerlist[0].String() != "2019-02-15T08:00:00Z Work @home @office" {
+		t.Errorf("Unexpected timers %v", timerlist)
+	}
+	if err := timerlist.LoadCSV(strings.NewReader("Notes,FinishDate\nWork,\n")); err == nil || !strings.Contains(err.Error(), "StartDate") {
+		t.Errorf("Expected a missing StartDate column error, got %v", err)
+	}
+}
+
+func TestWriteICS(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00-06:00 2019-02-15T07:30:00-06:00 Lunch, then coffee @home +food",
+		"2019-02-15T08:00:00Z Open",
+		"x 2019-02-16T09:00:00Z 2019-02-16T10:00:00Z Review",
+	}, "\n"))
+	var buf bytes.Buffer
+	if err := timerlist.WriteICS(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "BEGIN:VEVENT\r\n"); n != 2 {
+		t.Errorf("Expected 2 events, got %d", n)
+	}
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"DTSTART:20190215T120000Z\r\n",
+		"DTEND:20190215T133000Z\r\n",
+		"SUMMARY:Lunch\\, then coffee\r\n",
+		"CATEGORIES:@home,+food\r\n",
+		"DTSTART:20190216T090000Z\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got %q", want, out)
+		}
+	}
+	if strings.Contains(out, "SUMMARY:Open") {
+		t.Error("Expected the active timer to be skipped")
+	}
+}
+
+func TestTimerListClone(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z Work @home due:tomorrow\n")
+	clone := timerlist.Clone()
+	(*clone)[0].AdditionalTags["due"] = "today"
+	filtered := timerlist.Filter(func(Timer) bool { return true })
+	(*filtered)[0].Contexts[0] = "office"
+	if timerlist[0].AdditionalTags["due"] != "tomorrow" || timerlist[0].Contexts[0] != "home" {
+		t.Errorf("Expected the source to be unchanged, got %v", timerlist[0])
+	}
+}
+
+func TestStats(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1 @home +a",
+		"x 2019-02-15T08:00:00Z 2019-02-15T08:30:00Z Done2 @home @office +b",
+		"2019-02-15T10:00:00Z Open @office +a lastactive:2019-02-15T10:15:00Z",
+	}, "\n"))
+	stats := timerlist.Stats()
+	if stats.TotalCount != 3 || stats.ActiveCount != 1 || stats.FinishedCount != 2 {
+		t.Errorf("Expected 3 timers, 1 active and 2 finished, got %+v", stats)
+	}
+	if stats.TotalDuration != 105*time.Minute {
+		t.Errorf("Expected a total of 1h45m, got %v", stats.TotalDuration)
+	}
+	if len(stats.ContextCounts) != 2 || stats.ContextCounts["home"] != 2 || stats.ContextCounts["office"] != 2 {
+		t.Errorf("Unexpected context counts %v", stats.ContextCounts)
+	}
+	if len(stats.ProjectCounts) != 2 || stats.ProjectCounts["a"] != 2 || stats.ProjectCounts["b"] != 1 {
+		t.Errorf("Unexpected project counts %v", stats.ProjectCounts)
+	}
+}
+
+func TestGetTimersOnDay(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z SameDay",
+		"x 2019-02-14T22:00:00Z 2019-02-17T02:00:00Z MultiDay",
+		"2019-02-13T09:00:00Z Open",
+		"x 2019-02-16T06:00:00Z 2019-02-16T07:00:00Z OtherDay",
+		"2019-02-16T09:00:00Z OpenLater",
+	}, "\n"))
+	day := time.Date(2019, 2, 15, 12, 0, 0, 0, time.UTC)
+	if got := notesOf(*timerlist.GetTimersOnDay(day)); got != "SameDay MultiDay Open" {
+		t.Errorf("Expected SameDay, MultiDay and Open, got %q", got)
+	}
+}
+
+func TestMergeOverlapping(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T10:05:00Z 2019-02-15T11:00:00Z Work @office",
+		"x 2019-02-15T09:00:00Z 2019-02-15T10:00:00Z Work @office",
+		"x 2019-02-15T09:30:00Z 2019-02-15T10:30:00Z Other @office",
+	}, "\n"))
+	merged := timerlist.MergeOverlapping(10 * time.Minute)
+	if len(*merged) != 2 {
+		t.Fatalf("Expected 2 timers, got %v", merged)
+	}
+	work := (*merged)[0]
+	if work.Notes != "Work" || work.Id != 2 || work.Duration() != 2*time.Hour {
+		t.Errorf("Expected the Work timers merged into 2h, got %v (Id %d)", work, work.Id)
+	}
+	if work.Raw() != work.String() {
+		t.Errorf("Expected Raw() of a merged timer to match String(), got %q", work.Raw())
+	}
+	if other := (*merged)[1]; other.Notes != "Other" || other.Duration() != time.Hour {
+		t.Errorf("Expected Other to stay unmerged, got %v", other)
+	}
+	if len(timerlist) != 3 || timerlist[0].Duration() != 55*time.Minute {
+		t.Errorf("Expected the original list to be unchanged, got %v", timerlist)
+	}
+	if merged := timerlist.MergeOverlapping(time.Minute); len(*merged) != 3 {
+		t.Errorf("Expected no merges with a 1m gap, got %v", merged)
+	}
+}
+
+func TestSplitAtMidnight(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T22:00:00-06:00 2019-02-16T01:30:00-06:00 Late @home due:soon\n")
+	split := timerlist.SplitAtMidnight()
+	if len(*split) != 2 {
+		t.Fatalf("Expected 2 timers, got %v", split)
+	}
+	want := []string{
+		"x 2019-02-15T22:00:00-06:00 2019-02-16T00:00:00-06:00 Late @home due:soon",
+		"x 2019-02-16T00:00:00-06:00 2019-02-16T01:30:00-06:00 Late @home due:soon",
+	}
+	var total time.Duration
+	for i, part := range *split {
+		if part.String() != want[i] || part.Raw() != want[i] {
+			t.Errorf("Expected %q, got %q (raw %q)", want[i], part.String(), part.Raw())
+		}
+		total += part.Duration()
+	}
+	if total != timerlist[0].Duration() {
+		t.Errorf("Expected the parts to add up to %v, got %v", timerlist[0].Duration(), total)
+	}
+}
+
+func TestLoadWithDifferentLayouts(t *testing.T) {
+	tests := []struct {
+		layout, text string
+	}{
+		{"2006-01-02_15:04", "x 2019-02-15_06:00 2019-02-15_07:00 Underscores"},
+		{"2006/01/02T15:04", "x 2019/02/15T06:00 2019/02/15T07:00 Slashes"},
+	}
+	var wg sync.WaitGroup
+	for _, tt := range tests {
+		wg.Add(1)
+		go func(layout, text string) {
+			defer wg.Done()
+			var timerlist TimerList
+			for i := 0; i < 100; i++ {
+				if err := timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{DateLayout: layout}); err != nil {
+					t.Error(err)
+					return
+				}
+				if s := timerlist.String(); s != text+"\n" {
+					t.Errorf("Expected %q, got %q", text, s)
+					return
+				}
+			}
+		}(tt.layout, tt.text)
+	}
+	wg.Wait()
+	if DateLayout != time.RFC3339 {
+		t.Errorf("Expected the package DateLayout to be unchanged, got %q", DateLayout)
+	}
+}
+
+func TestWriteToFilenameSorted(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T10:00:00Z Third\n2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n")
+	filename := filepath.Join(t.TempDir(), "timer.txt")
+	if err := timerlist.WriteToFilenameSorted(filename, SORT_START_DATE_ASC); err != nil {
+		t.Fatal(err)
+	}
+	if got := notesOf(timerlist); got != "Third First Second" {
+		t.Errorf("Expected the list to keep its order, got %q", got)
+	}
+	loaded, err := LoadFromFilename(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notesOf(loaded); got != "First Second Third" {
+		t.Errorf("Expected the file to be sorted, got %q", got)
+	}
+	if err := timerlist.WriteToFilenameSorted(filename, -1); err == nil {
+		t.Error("Expected an error for an unknown sort flag")
+	}
+}
+
+// TestSyncTimerListConcurrent is meant to be run with -race.
+func TestSyncTimerListConcurrent(t *testing.T) {
+	s := NewSyncTimerList()
+	start := time.Date(2019, 2, 15, 8, 0, 0, 0, time.UTC)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				timer := NewTimer().WithStart(start.Add(time.Duration(i*50+j) * time.Minute)).WithNotes("Work")
+				s.AddTimer(timer)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				s.GetTimer(1)
+				s.Filter(func(t Timer) bool { return t.Notes == "Work" })
+				s.WriteTo(io.Discard)
+				_ = s.String()
+			}
+		}()
+	}
+	wg.Wait()
+	if n := len(*s.TimerList()); n != 400 {
+		t.Errorf("Expected 400 timers, got %d", n)
+	}
+}
+
+func TestLongestAndShortestTimer(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Hour",
+		"x 2019-02-15T08:00:00Z 2019-02-15T08:05:00Z Short",
+		"x 2019-02-15T09:00:00Z 2019-02-15T12:00:00Z Long",
+	}, "\n"))
+	if longest, err := timerlist.GetLongestTimer(); err != nil || longest.Notes != "Long" {
+		t.Errorf("Expected Long, got %v, %v", longest, err)
+	}
+	if shortest, err := timerlist.GetShortestTimer(); err != nil || shortest.Notes != "Short" {
+		t.Errorf("Expected Short, got %v, %v", shortest, err)
+	}
+	// An open timer started days ago is measured up to now
+	timerlist.AppendTimer(&Timer{StartDate: time.Now().AddDate(0, 0, -2), Notes: "Open"})
+	if longest, err := timerlist.GetLongestTimer(); err != nil || longest.Notes != "Open" {
+		t.Errorf("Expected Open, got %v, %v", longest, err)
+	}
+	var empty TimerList
+	if _, err := empty.GetLongestTimer(); err == nil {
+		t.Error("Expected an error for an empty list")
+	}
+	if _, err := empty.GetShortestTimer(); err == nil {
+		t.Error("Expected an error for an empty list")
+	}
+}
+
+func TestOverlappingPairs(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T08:00:00Z 2019-02-15T10:00:00Z Overlapping",
+		"x 2019-02-15T09:00:00Z 2019-02-15T11:00:00Z Overlapped",
+		"x 2019-02-15T11:00:00Z 2019-02-15T12:00:00Z BackToBack",
+	}, "\n"))
+	pairs := timerlist.OverlappingPairs()
+	if len(pairs) != 1 || pairs[0] != [2]int{1, 2} {
+		t.Errorf("Expected only timers 1 and 2 to overlap, got %v", pairs)
+	}
+}
+
+func TestRoundedDuration(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T08:00:00Z 2019-02-15T08:07:00Z Seven",
+		"x 2019-02-15T09:00:00Z 2019-02-15T09:15:00Z Fifteen",
+		"x 2019-02-15T10:00:00Z 2019-02-15T10:16:00Z Sixteen",
+	}, "\n"))
+	if d := timerlist[0].RoundedDuration(15 * time.Minute); d != 15*time.Minute {
+		t.Errorf("Expected 7m to round up to 15m, got %v", d)
+	}
+	if d := timerlist[0].NearestRoundedDuration(15 * time.Minute); d != 0 {
+		t.Errorf("Expected 7m to round to 0, got %v", d)
+	}
+	if d := timerlist[1].RoundedDuration(15 * time.Minute); d != 15*time.Minute {
+		t.Errorf("Expected 15m to stay 15m, got %v", d)
+	}
+	// Each timer is rounded separately: 15m + 15m + 30m, where the 38m total would round to 45m
+	if d := timerlist.TotalRoundedDuration(15 * time.Minute); d != time.Hour {
+		t.Errorf("Expected 1h, got %v", d)
+	}
+}
+
+func TestNextIdAndAppendTimer(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n2019-02-15T10:00:00Z Third\n")
+	if id := timerlist.NextId(); id != 4 {
+		t.Errorf("Expected NextId 4, got %d", id)
+	}
+	if err := timerlist.RemoveTimerById(3); err != nil {
+		t.Fatal(err)
+	}
+	if id := timerlist.NextId(); id != 3 {
+		t.Errorf("Expected NextId 3 after removing the highest Id, got %d", id)
+	}
+	if err := timerlist.RemoveTimerById(1); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[int]bool)
+	for _, timer := range timerlist {
+		seen[timer.Id] = true
+	}
+	for i := 0; i < 3; i++ {
+		timer := &Timer{StartDate: time.Date(2019, 2, 16, i, 0, 0, 0, time.UTC)}
+		timerlist.AppendTimer(timer)
+		if seen[timer.Id] {
+			t.Errorf("Expected a new Id, got %d again", timer.Id)
+		}
+		seen[timer.Id] = true
+	}
+	if timerlist[0].Id != 2 || timerlist[len(timerlist)-1].Id != 5 {
+		t.Errorf("Expected Ids 2 through 5, got %v", timerlist)
+	}
+}
+
+func TestGzipRoundTrip(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00-06:00 2019-02-15T10:00:00-06:00 Done @home\n2019-02-15T11:43:00-06:00 Open +timertxt\n")
+	filename := filepath.Join(t.TempDir(), "timer.txt.gz")
+	if err := timerlist.WriteToGzipFilename(filename); err != nil {
+		t.Fatal(err)
+	}
+	var loaded TimerList
+	if err := loaded.LoadFromGzipFilename(filename); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.String() != timerlist.String() {
+		t.Errorf("Expected %q, got %q", timerlist.String(), loaded.String())
+	}
+	plain := filepath.Join(t.TempDir(), "timer.txt")
+	if err := timerlist.WriteToFilename(plain); err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.LoadFromGzipFilename(plain); err == nil {
+		t.Error("Expected an error loading an uncompressed file")
+	}
+}
+
+func TestFilterIndices(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First @home\n2019-02-15T09:00:00Z Second\n2019-02-15T10:00:00Z Third @home\n")
+	indices := timerlist.FilterIndices(func(t Timer) bool { return t.HasContext("home") })
+	if len(indices) != 2 || indices[0] != 0 || indices[1] != 2 {
+		t.Fatalf("Expected indices 0 and 2, got %v", indices)
+	}
+	for _, i := range indices {
+		timerlist[i].AddProject("chores")
+	}
+	if got := notesOf(*timerlist.GetTimersWithProject("chores")); got != "First Third" {
+		t.Errorf("Expected First and Third to be changed in place, got %q", got)
+	}
+}
+
+func TestForEach(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second +other\n")
+	timerlist.ForEach(func(t *Timer) {
+		t.AddProject("timertxt")
+	})
+	want := "2019-02-15T08:00:00Z First +timertxt\n2019-02-15T09:00:00Z Second +other +timertxt\n"
+	if s := timerlist.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+}
+
+func TestArchivePlan(t *testing.T) {
+	text := "x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1\n2019-02-15T08:00:00Z Open\nx 2019-02-15T09:00:00Z 2019-02-15T10:00:00Z Done2\n"
+	timerlist := loadTestList(t, text)
+	remaining, archived := timerlist.ArchivePlan(func(t Timer) bool { return t.Finished })
+	if got := notesOf(remaining); got != "Open" {
+		t.Errorf("Expected Open to remain, got %q", got)
+	}
+	if got := notesOf(archived); got != "Done1 Done2" {
+		t.Errorf("Expected Done1 and Done2 to be archived, got %q", got)
+	}
+	if timerlist.String() != text {
+		t.Errorf("Expected the list to be unchanged, got %q", timerlist.String())
+	}
+}
+
+func TestArchiveTimersToFile(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1\n2019-02-15T08:00:00Z Open\nx 2019-02-15T09:00:00Z 2019-02-15T10:00:00Z Done2\n")
+	filename := filepath.Join(t.TempDir(), "done.txt")
+	// No final newline, as left by some editors
+	if err := os.WriteFile(filename, []byte("x 2019-02-14T09:00:00Z 2019-02-14T10:00:00Z Old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	n, err := timerlist.ArchiveTimersToFile(func(t Timer) bool { return t.Finished }, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 archived timers, got %d", n)
+	}
+	if got := notesOf(timerlist); got != "Open" {
+		t.Errorf("Expected Open to remain, got %q", got)
+	}
+	archive, err := LoadFromFilename(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := notesOf(archive); got != "Old Done1 Done2" {
+		t.Errorf("Expected the timers appended to the archive, got %q", got)
+	}
+
+	timerlist = loadTestList(t, "x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1\n")
+	missing := filepath.Join(t.TempDir(), "missing", "done.txt")
+	if _, err := timerlist.ArchiveTimersToFile(func(t Timer) bool { return t.Finished }, missing); err == nil {
+		t.Error("Expected an error archiving into a missing directory")
+	}
+	if len(timerlist) != 1 {
+		t.Errorf("Expected the timer to stay in the list, got %v", timerlist)
+	}
+}
+
+func TestWriteToFilenameAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "timer.txt")
+	if err := os.WriteFile(filename, []byte("2019-02-14T08:00:00Z Old\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n")
+	if err := timerlist.WriteToFilename(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != timerlist.String() {
+		t.Errorf("Expected %q, got %q", timerlist.String(), data)
+	}
+	info, err := os.Stat(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file mode to be kept, got %v", info.Mode())
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected only timer.txt to be left, got %v", entries)
+	}
+}
+
+func TestRenameProject(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T08:00:00Z First +old",
+		"2019-02-15T09:00:00Z Second +old +new",
+		"2019-02-15T10:00:00Z Third +other",
+	}, "\n"))
+	if n := timerlist.RenameProject("old", "new"); n != 2 {
+		t.Errorf("Expected 2 timers changed, got %d", n)
+	}
+	want := "2019-02-15T08:00:00Z First +new\n2019-02-15T09:00:00Z Second +new\n2019-02-15T10:00:00Z Third +other\n"
+	if s := timerlist.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+	if n := timerlist.RenameProject("missing", "new"); n != 0 {
+		t.Errorf("Expected no timers changed, got %d", n)
+	}
+	if n := timerlist.RenameContext("missing", "home"); n != 0 {
+		t.Errorf("Expected no timers changed, got %d", n)
+	}
+}
+
+func TestDistinctProjectsContextsAndTags(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T08:00:00Z First @office +work due:today",
+		"2019-02-15T09:00:00Z Second @home +work +chores billable:no",
+		"2019-02-15T10:00:00Z Third @home +work due:tomorrow",
+	}, "\n"))
+	if got := strings.Join(timerlist.Projects(), " "); got != "chores work" {
+		t.Errorf("Expected chores and work, got %q", got)
+	}
+	if got := strings.Join(timerlist.Contexts(), " "); got != "home office" {
+		t.Errorf("Expected home and office, got %q", got)
+	}
+	if got := strings.Join(timerlist.Tags(), " "); got != "billable due" {
+		t.Errorf("Expected billable and due, got %q", got)
+	}
+	var empty TimerList
+	if projects := empty.Projects(); projects == nil || len(projects) != 0 {
+		t.Errorf("Expected an empty slice, got %#v", projects)
+	}
+}
+
+func TestStartedAndFinishedBetween(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T08:00:00Z 2019-02-15T09:00:00Z Inside",
+		"x 2019-02-15T22:00:00Z 2019-02-16T02:00:00Z StartsInside",
+		"x 2019-02-14T22:00:00Z 2019-02-15T00:00:00Z FinishesInside",
+		"2019-02-15T23:59:59Z Open",
+	}, "\n"))
+	start := time.Date(2019, 2, 15, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 2, 15, 23, 59, 59, 0, time.UTC)
+	if got := notesOf(*timerlist.GetTimersStartedBetween(start, end)); got != "Inside StartsInside Open" {
+		t.Errorf("Expected Inside, StartsInside and Open, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersFinishedBetween(start, end)); got != "Inside FinishesInside" {
+		t.Errorf("Expected Inside and FinishesInside, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersInRange(start, end)); got != "Inside StartsInside FinishesInside Open" {
+		t.Errorf("Expected every timer, got %q", got)
+	}
+}
+
+func TestInvalidTimers(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T08:00:00Z 2019-02-15T09:00:00Z Normal",
+		"x 2019-02-15T11:00:00Z 2019-02-15T10:00:00Z Reversed",
+		"2019-02-15T12:00:00Z Open",
+	}, "\n"))
+	invalid := timerlist.InvalidTimers()
+	if got := notesOf(*invalid); got != "Reversed" {
+		t.Fatalf("Expected only Reversed to be invalid, got %q", got)
+	}
+	if timerlist[0].SwapDatesIfReversed() {
+		t.Error("Expected a normal timer not to be swapped")
+	}
+	if !timerlist[1].SwapDatesIfReversed() || !timerlist[1].HasValidInterval() || timerlist[1].Duration() != time.Hour {
+		t.Errorf("Expected the reversed timer to be fixed, got %v", timerlist[1])
+	}
+	if n := len(*timerlist.InvalidTimers()); n != 0 {
+		t.Errorf("Expected no invalid timers left, got %d", n)
+	}
+}
+
+// benchmarkTimerList returns a TimerList of n finished timers for benchmarks.
+func benchmarkTimerList(n int) TimerList {
+	start := time.Date(2019, 2, 15, 8, 0, 0, 0, time.UTC)
+	timerlist := make(TimerList, n)
+	for i := range timerlist {
+		s := start.Add(time.Duration(i) * time.Hour)
+		timerlist[i] = Timer{
+			Id:             i + 1,
+			StartDate:      s,
+			FinishDate:     s.Add(45 * time.Minute),
+			Finished:       true,
+			Notes:          "Working on Go Library",
+			Contexts:       []string{"home", "personal"},
+			Projects:       []string{"timertxt"},
+			AdditionalTags: map[string]string{"due": "Today"},
+		}
+	}
+	return timerlist
+}
+
+func BenchmarkWriteTo(b *testing.B) {
+	timerlist := benchmarkTimerList(50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := timerlist.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkWriteToViaString writes the whole list as one string first, for comparison with BenchmarkWriteTo.
+func BenchmarkWriteToViaString(b *testing.B) {
+	timerlist := benchmarkTimerList(50000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := io.WriteString(io.Discard, timerlist.String()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestWriteToKeepsContextOrder(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z Work @office @home +zeta +alpha\n")
+	contexts := strings.Join(timerlist[0].Contexts, " ")
+	projects := strings.Join(timerlist[0].Projects, " ")
+	if _, err := timerlist.WriteTo(io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	_ = timerlist.String()
+	if got := strings.Join(timerlist[0].Contexts, " "); got != contexts {
+		t.Errorf("Expected Contexts %q, got %q", contexts, got)
+	}
+	if got := strings.Join(timerlist[0].Projects, " "); got != projects {
+		t.Errorf("Expected Projects %q, got %q", projects, got)
+	}
+}
+
+func TestConcatAndAppendList(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n")
+	archive := loadTestList(t, "2019-02-14T08:00:00Z Old\n2019-02-15T09:00:00Z Second\n")
+	all := timerlist.Concat(archive)
+	if got := notesOf(*all); got != "First Second Old Second" {
+		t.Errorf("Expected both lists, got %q", got)
+	}
+	for i, timer := range *all {
+		if timer.Id != i+1 {
+			t.Errorf("Expected Id %d, got %d", i+1, timer.Id)
+		}
+	}
+	if got := notesOf(*timerlist.ConcatUnique(archive)); got != "First Second Old" {
+		t.Errorf("Expected the duplicate to be dropped, got %q", got)
+	}
+	if len(timerlist) != 2 {
+		t.Errorf("Expected the list to be unchanged, got %v", timerlist)
+	}
+	timerlist.AppendList(archive)
+	seen := make(map[int]bool)
+	for _, timer := range timerlist {
+		if seen[timer.Id] {
+			t.Errorf("Expected unique Ids, got %d twice", timer.Id)
+		}
+		seen[timer.Id] = true
+	}
+	if len(timerlist) != 4 {
+		t.Errorf("Expected 4 timers, got %d", len(timerlist))
+	}
+}
+
+func TestPageAndChunk(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T08:00:00Z One",
+		"2019-02-15T09:00:00Z Two",
+		"2019-02-15T10:00:00Z Three",
+		"2019-02-15T11:00:00Z Four",
+		"2019-02-15T12:00:00Z Five",
+	}, "\n"))
+	tests := []struct {
+		offset, limit int
+		notes         string
+	}{
+		{0, 2, "One Two"},
+		{4, 2, "Five"},
+		{5, 2, ""},
+		{10, 2, ""},
+		{1, 0, ""},
+		{-1, 1, "One"},
+	}
+	for _, tt := range tests {
+		if got := notesOf(*timerlist.Page(tt.offset, tt.limit)); got != tt.notes {
+			t.Errorf("Page(%d, %d): Expected %q, got %q", tt.offset, tt.limit, tt.notes, got)
+		}
+	}
+	page := timerlist.Page(0, 1)
+	(*page)[0].Notes = "Changed"
+	if timerlist[0].Notes != "One" {
+		t.Error("Expected the list to be unchanged")
+	}
+	chunks := timerlist.Chunk(2)
+	if len(chunks) != 3 || notesOf(*chunks[2]) != "Five" {
+		t.Errorf("Expected 3 chunks with a partial last one, got %v", chunks)
+	}
+	if chunks := timerlist.Chunk(0); chunks != nil {
+		t.Errorf("Expected nil, got %v", chunks)
+	}
+}
+
+func TestWriteNewlines(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n")
+	for _, newline := range []string{LF, CRLF} {
+		var buf bytes.Buffer
+		if _, err := timerlist.WriteToWithOptions(&buf, WriteOptions{Newline: newline}); err != nil {
+			t.Fatal(err)
+		}
+		want := "2019-02-15T08:00:00Z First" + newline + "2019-02-15T09:00:00Z Second" + newline
+		if buf.String() != want {
+			t.Errorf("Expected %q, got %q", want, buf.String())
+		}
+		// Reading the output back keeps its line endings
+		loaded := loadTestList(t, buf.String())
+		if loaded.String() != want || loaded[0].Notes != "First" {
+			t.Errorf("Expected %q, got %q", want, loaded.String())
+		}
+	}
+	filename := filepath.Join(t.TempDir(), "timer.txt")
+	if err := timerlist.WriteToFilenameWithOptions(filename, WriteOptions{Newline: CRLF}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "\r\n") != 2 {
+		t.Errorf("Expected CRLF line endings, got %q", data)
+	}
+}
+
+func TestCRLFFileRoundTrip(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "timer.txt")
+	text := "2019-02-15T08:00:00Z First\r\n# note\r\n2019-02-15T09:00:00Z Second\r\n"
+	if err := os.WriteFile(filename, []byte(text), 0600); err != nil {
+		t.Fatal(err)
+	}
+	timerlist, err := LoadFromFilename(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	timerlist.AppendTimer(&Timer{StartDate: time.Date(2019, 2, 15, 10, 0, 0, 0, time.UTC), Notes: "Third"})
+	if err := timerlist.WriteToFilename(filename); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "2019-02-15T08:00:00Z First\r\n2019-02-15T09:00:00Z Second\r\n2019-02-15T10:00:00Z Third\r\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
+
+func TestGetTimersSinceAndToday(t *testing.T) {
+	now := time.Date(2019, 2, 15, 12, 0, 0, 0, time.Local)
+	timerlist := TimerList{
+		{Id: 1, StartDate: now.Add(-30 * time.Hour), Notes: "DayBefore"},
+		{Id: 2, StartDate: now.Add(-20 * time.Hour), Notes: "Yesterday"},
+		{Id: 3, StartDate: time.Date(2019, 2, 15, 0, 0, 0, 0, time.Local), Notes: "Midnight"},
+		{Id: 4, StartDate: now.Add(-time.Hour), Notes: "Recent"},
+		{Id: 5, StartDate: time.Date(2019, 2, 15, 23, 0, 0, 0, time.Local), Notes: "Tonight"},
+		{Id: 6, StartDate: time.Date(2019, 2, 16, 0, 0, 0, 0, time.Local), Notes: "Tomorrow"},
+	}
+	if got := notesOf(*timerlist.getTimersSince(24*time.Hour, now)); got != "Yesterday Midnight Recent" {
+		t.Errorf("Expected the timers of the last 24h, got %q", got)
+	}
+	if got := notesOf(*timerlist.getTimersToday(now)); got != "Midnight Recent Tonight" {
+		t.Errorf("Expected the timers of today, got %q", got)
+	}
+	// 2019-02-15 is a Friday, so the week started on Monday 2019-02-11
+	if got := notesOf(*timerlist.getTimersThisWeek(now)); got != "DayBefore Yesterday Midnight Recent Tonight Tomorrow" {
+		t.Errorf("Expected the timers of this week, got %q", got)
+	}
+}
+
+func TestSortByCustomLess(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Hour",
+		"x 2019-02-15T08:00:00Z 2019-02-15T11:00:00Z ThreeHours",
+		"x 2019-02-15T12:00:00Z 2019-02-15T12:30:00Z HalfHour",
+		"x 2019-02-15T05:00:00Z 2019-02-15T06:00:00Z EarlyHour",
+	}, "\n"))
+	timerlist.SortBy(func(a, b Timer) bool {
+		return a.Duration() > b.Duration()
+	})
+	// Ties are broken by StartDate
+	if got := notesOf(timerlist); got != "ThreeHours EarlyHour Hour HalfHour" {
+		t.Errorf("Expected the timers by duration descending, got %q", got)
+	}
+}
+
+func TestSortByDuration(t *testing.T) {
+	text := strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T09:00:00Z ThreeHours",
+		"x 2019-02-15T10:00:00Z 2019-02-15T11:00:00Z Hour",
+		"2019-02-14T10:00:00Z Open",
+	}, "\n")
+	tests := []struct {
+		flag  int
+		notes string
+	}{
+		{SORT_DURATION_ASC, "Hour ThreeHours Open"},
+		{SORT_DURATION_DESC, "Open ThreeHours Hour"},
+	}
+	for _, tt := range tests {
+		timerlist := loadTestList(t, text)
+		if err := timerlist.Sort(tt.flag); err != nil {
+			t.Fatal(err)
+		}
+		if got := notesOf(timerlist); got != tt.notes {
+			t.Errorf("Sort(%d): Expected %q, got %q", tt.flag, tt.notes, got)
+		}
+	}
+}
+
+func TestSortByNotes(t *testing.T) {
+	text := "2019-02-15T06:00:00Z Zebra\n2019-02-15T07:00:00Z apple\n2019-02-15T08:00:00Z Mango\n2019-02-15T05:00:00Z Apple\n"
+	tests := []struct {
+		flag  int
+		notes string
+	}{
+		{SORT_NOTES_ASC, "Apple apple Mango Zebra"},
+		{SORT_NOTES_DESC, "Zebra Mango Apple apple"},
+	}
+	for _, tt := range tests {
+		timerlist := loadTestList(t, text)
+		if err := timerlist.Sort(tt.flag); err != nil {
+			t.Fatal(err)
+		}
+		if got := notesOf(timerlist); got != tt.notes {
+			t.Errorf("Sort(%d): Expected %q, got %q", tt.flag, tt.notes, got)
+		}
+	}
+}
+
+// cancelingReader returns one line per Read, and calls cancel once 'after' lines have been read.
+type cancelingReader struct {
+	lines  []string
+	after  int
+	cancel context.CancelFunc
+}
+
+func (r *cancelingReader) Read(p []byte) (int, error) {
+	if len(r.lines) == 0 {
+		return 0, io.EOF
+	}
+	if r.after--; r.after == 0 {
+		r.cancel()
+	}
+	n := copy(p, r.lines[0])
+	r.lines = r.lines[1:]
+	return n, nil
+}
+
+func TestLoadFromContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelingReader{after: 3, cancel: cancel}
+	for i := 0; i < 10; i++ {
+		r.lines = append(r.lines, fmt.Sprintf("2019-02-15T%02d:00:00Z Timer %d\n", i, i))
+	}
+	timerlist := loadTestList(t, "2019-02-14T08:00:00Z Old\n")
+	err := timerlist.LoadFromContext(ctx, r)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if len(timerlist) != 0 {
+		t.Errorf("Expected the list to be cleared, got %v", timerlist)
+	}
+	if len(r.lines) == 0 {
+		t.Error("Expected loading to stop before the end of the input")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T08:00:00Z Fixing the Parser @work",
+		"2019-02-15T09:00:00Z Lunch +parser",
+		"2019-02-15T10:00:00Z Writing docs",
+	}, "\n"))
+	if got := notesOf(*timerlist.Search("parser", false)); got != "Fixing the Parser" {
+		t.Errorf("Expected a case-insensitive Notes match, got %q", got)
+	}
+	if got := notesOf(*timerlist.Search("parser", true)); got != "Fixing the Parser Lunch" {
+		t.Errorf("Expected the project to match too, got %q", got)
+	}
+	if got := timerlist.Search("missing", true); len(*got) != 0 {
+		t.Errorf("Expected no matches, got %v", got)
+	}
+	re := regexp.MustCompile(`^(Lunch|Writing)`)
+	if got := notesOf(*timerlist.SearchRegex(re, false)); got != "Lunch Writing docs" {
+		t.Errorf("Expected Lunch and Writing docs, got %q", got)
+	}
+}
+
+func TestClearAndLen(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n")
+	if timerlist.Len() != 2 {
+		t.Errorf("Expected Len 2, got %d", timerlist.Len())
+	}
+	capacity := cap(timerlist)
+	timerlist.Clear()
+	if timerlist.Len() != 0 || timerlist.String() != "" {
+		t.Errorf("Expected an empty list, got %q", timerlist.String())
+	}
+	if cap(timerlist) != capacity {
+		t.Errorf("Expected the capacity %d to be kept, got %d", capacity, cap(timerlist))
+	}
+}
+
+func TestReverse(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n2019-02-15T10:00:00Z Third\n")
+	timerlist.Reverse()
+	if got := notesOf(timerlist); got != "Third Second First" {
+		t.Errorf("Expected the reversed order, got %q", got)
+	}
+	for i, id := range []int{3, 2, 1} {
+		if timerlist[i].Id != id {
+			t.Errorf("Expected %s to keep Id %d, got %d", timerlist[i].Notes, id, timerlist[i].Id)
+		}
+	}
+}
+
+func TestKeepIndentRoundTrip(t *testing.T) {
+	text := "2019-02-15T08:00:00Z Top level @home\n\t2019-02-15T09:00:00Z Indented with a tab +timertxt\n  \t2019-02-15T10:00:00Z Mixed indent\n"
+	var timerlist TimerList
+	if err := timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{KeepIndent: true}); err != nil {
+		t.Fatal(err)
+	}
+	if timerlist[1].Indent != "\t" || timerlist[1].Notes != "Indented with a tab" {
+		t.Errorf("Expected a tab indent, got %q", timerlist[1].Indent)
+	}
+	var buf bytes.Buffer
+	if _, err := timerlist.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != text {
+		t.Errorf("Expected %q, got %q", text, buf.String())
+	}
+	plain := loadTestList(t, text)
+	if s := plain.String(); strings.Contains(s, "\t") {
+		t.Errorf("Expected the indent to be dropped by default, got %q", s)
+	}
+}
+
+func TestComments(t *testing.T) {
+	text := "# Monday\n2019-02-15T08:00:00Z First\n  # still Monday\n2019-02-15T09:00:00Z Second\n# end of file\n"
+	plain := loadTestList(t, text)
+	if got := notesOf(plain); got != "First Second" {
+		t.Errorf("Expected comment lines to be skipped, got %q", got)
+	}
+	if s := plain.String(); strings.Contains(s, "#") {
+		t.Errorf("Expected the comments to be dropped by default, got %q", s)
+	}
+	var timerlist TimerList
+	if err := timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{KeepComments: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(timerlist) != 2 || timerlist[0].Id != 1 || timerlist[1].Id != 2 {
+		t.Fatalf("Expected 2 timers, got %v", timerlist)
+	}
+	if s := timerlist.String(); s != text {
+		t.Errorf("Expected %q, got %q", text, s)
+	}
+}
+
+func TestGroupByDay(t *testing.T) {
+	defer func(local *time.Location) { time.Local = local }(time.Local)
+	time.Local = time.UTC
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-16T09:00:00Z 2019-02-16T10:00:00Z Saturday",
+		"x 2019-02-15T08:00:00Z 2019-02-15T09:00:00Z Friday",
+		// Friday in its own time zone, but Saturday in local time
+		"x 2019-02-15T23:00:00-06:00 2019-02-15T23:30:00-06:00 FridayNight",
+	}, "\n"))
+	days := timerlist.GroupByDay()
+	if len(days) != 2 {
+		t.Fatalf("Expected 2 days, got %v", days)
+	}
+	if got := notesOf(*days["2019-02-15"]); got != "Friday" {
+		t.Errorf("Expected Friday on 2019-02-15, got %q", got)
+	}
+	if got := notesOf(*days["2019-02-16"]); got != "Saturday FridayNight" {
+		t.Errorf("Expected Saturday and FridayNight on 2019-02-16, got %q", got)
+	}
+	if got := strings.Join(timerlist.SortedDays(), " "); got != "2019-02-15 2019-02-16" {
+		t.Errorf("Expected the days in order, got %q", got)
+	}
+}
+
+func TestWriteToFilenameMkdirAll(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n")
+	filename := filepath.Join(t.TempDir(), "config", "app", "timer.txt")
+	if err := timerlist.WriteToFilename(filename); err == nil {
+		t.Error("Expected WriteToFilename to fail for a missing directory")
+	}
+	if err := timerlist.WriteToFilenameMkdirAll(filename); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadFromFilename(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.String() != timerlist.String() {
+		t.Errorf("Expected %q, got %q", timerlist.String(), loaded.String())
+	}
+}
+
+func TestFinishedDuration(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"x 2019-02-15T06:00:00Z 2019-02-15T07:00:00Z Done1",
+		"2019-02-15T08:00:00Z Open",
+		"x 2019-02-15T09:00:00Z 2019-02-15T09:30:00Z Done2",
+	}, "\n"))
+	if d := timerlist[1].FinishedDuration(); d != 0 {
+		t.Errorf("Expected 0 for an open timer, got %v", d)
+	}
+	if d := timerlist[0].FinishedDuration(); d != time.Hour {
+		t.Errorf("Expected 1h, got %v", d)
+	}
+	if d := timerlist.TotalFinishedDuration(); d != 90*time.Minute {
+		t.Errorf("Expected 1h30m of finished work, got %v", d)
+	}
+	if d := timerlist.TotalDuration(); d <= 90*time.Minute {
+		t.Errorf("Expected TotalDuration to include the open timer, got %v", d)
+	}
+}
+
+func TestCSVOptionsRoundTrip(t *testing.T) {
+	timerlist := loadTestList(t, "x 2019-02-15T06:00:00Z 2019-02-15T07:30:00Z Lunch, then coffee @home @cafe\n2019-02-15T08:00:00Z Open\n")
+	opts := CSVOptions{
+		Columns:       []string{"StartDate", "FinishDate", "Notes", "Contexts"},
+		Comma:         '\t',
+		OmitHeader:    true,
+		ListSeparator: ";",
+	}
+	var buf bytes.Buffer
+	if err := timerlist.WriteCSVWithOptions(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	want := "2019-02-15T06:00:00Z\t2019-02-15T07:30:00Z\tLunch, then coffee\thome;cafe\n2019-02-15T08:00:00Z\t\tOpen\t\n"
+	if buf.String() != want {
+		t.Errorf("Expected %q, got %q", want, buf.String())
+	}
+	var loaded TimerList
+	if err := loaded.LoadCSVWithOptions(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.String() != timerlist.String() {
+		t.Errorf("Expected %q, got %q", timerlist.String(), loaded.String())
+	}
+	if err := timerlist.WriteCSVWithOptions(&buf, CSVOptions{Columns: []string{"Bogus"}}); err == nil {
+		t.Error("Expected an error for an unknown column")
+	}
+}
+
+func TestAnyAndAllContexts(t *testing.T) {
+	timerlist := loadTestList(t, strings.Join([]string{
+		"2019-02-15T08:00:00Z HomeUrgent @home @urgent +a +b",
+		"2019-02-15T09:00:00Z Work @work +c",
+		"2019-02-15T10:00:00Z Home @home +a",
+	}, "\n"))
+	timer := timerlist[0]
+	if timer.HasAllContexts("home", "urgent", "work") || !timer.HasAllContexts("home", "urgent") {
+		t.Error("Expected HasAllContexts to need every context")
+	}
+	if !timer.HasAnyContext("home", "urgent", "work") || timer.HasAnyContext("work") {
+		t.Error("Expected HasAnyContext to need one of the contexts")
+	}
+	if timer.HasAllProjects("a", "b", "c") || !timer.HasAnyProject("a", "b", "c") {
+		t.Error("Expected the project variants to match the same way")
+	}
+	if got := notesOf(*timerlist.GetTimersWithAllContexts("home", "urgent")); got != "HomeUrgent" {
+		t.Errorf("Expected HomeUrgent, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersWithAnyContext("urgent", "work")); got != "HomeUrgent Work" {
+		t.Errorf("Expected HomeUrgent and Work, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersWithAllProjects("a")); got != "HomeUrgent Home" {
+		t.Errorf("Expected HomeUrgent and Home, got %q", got)
+	}
+	if got := notesOf(*timerlist.GetTimersWithAnyProject("b", "c")); got != "HomeUrgent Work" {
+		t.Errorf("Expected HomeUrgent and Work, got %q", got)
+	}
+}
+
+func TestLoadIdFromTag(t *testing.T) {
+	text := strings.Join([]string{
+		"2019-02-15T08:00:00Z Tagged id:7",
+		"2019-02-15T09:00:00Z Untagged",
+		"2019-02-15T10:00:00Z External id:abc123",
+		"2019-02-15T11:00:00Z Low id:2",
+		"2019-02-15T12:00:00Z Repeat id:7",
+	}, "\n")
+	var timerlist TimerList
+	if err := timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{IdFromTag: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := []int{7, 8, 9, 2, 10}
+	seen := make(map[int]bool)
+	for i, timer := range timerlist {
+		if timer.Id != want[i] {
+			t.Errorf("%s: Expected Id %d, got %d", timer.Notes, want[i], timer.Id)
+		}
+		if seen[timer.Id] {
+			t.Errorf("Expected unique Ids, got %d twice", timer.Id)
+		}
+		seen[timer.Id] = true
+	}
+	if v, _ := timerlist[0].GetTag("id"); v != "7" || !strings.Contains(timerlist.String(), "id:abc123") {
+		t.Error("Expected the id tags to be kept")
+	}
+	if timer, err := timerlist.GetTimerByTagId("abc123"); err != nil || timer.Notes != "External" {
+		t.Errorf("Expected External for id abc123, got %v, %v", timer, err)
+	}
+	if timer, err := timerlist.GetTimerByTagId("7"); err != nil || timer.Notes != "Tagged" {
+		t.Errorf("Expected the first timer tagged 7, got %v, %v", timer, err)
+	}
+	if _, err := timerlist.GetTimerByTagId("missing"); err == nil {
+		t.Error("Expected an error for an unknown id")
+	}
+	// Without the option, ids are positional
+	if plain := loadTestList(t, text); plain[0].Id != 1 {
+		t.Errorf("Expected Id 1, got %d", plain[0].Id)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldList := loadTestList(t, "2019-02-15T08:00:00Z Kept\n2019-02-15T09:00:00Z Deleted\n2019-02-15T10:00:00Z Modified\n")
+	newList := loadTestList(t, "2019-02-15T08:00:00Z Kept\n2019-02-15T10:00:00Z Modified @home\n2019-02-15T11:00:00Z Added\n")
+	added, removed, unchanged := Diff(oldList, newList)
+	if got := notesOf(added); got != "Modified Added" {
+		t.Errorf("Expected Modified and Added to be added, got %q", got)
+	}
+	if got := notesOf(removed); got != "Deleted Modified" {
+		t.Errorf("Expected Deleted and Modified to be removed, got %q", got)
+	}
+	if got := notesOf(unchanged); got != "Kept" {
+		t.Errorf("Expected Kept to be unchanged, got %q", got)
+	}
+	if added[0].Id != 2 || removed[1].Id != 3 {
+		t.Errorf("Expected the Ids of their own lists, got %d and %d", added[0].Id, removed[1].Id)
+	}
+}
+
+func TestSetDatePrecision(t *testing.T) {
+	text := "x 2019-02-15T08:00:00.123456789Z 2019-02-15T09:00:00.5Z Work\n"
+	var timerlist TimerList
+	if err := timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{DateLayout: time.RFC3339Nano}); err != nil {
+		t.Fatal(err)
+	}
+	if s := timerlist.String(); s != text {
+		t.Errorf("Expected fractional seconds to be kept by default, got %q", s)
+	}
+	other := timerlist.Clone()
+	timerlist.SetDatePrecision(time.Second)
+	if s := timerlist.String(); s != "x 2019-02-15T08:00:00Z 2019-02-15T09:00:00Z Work\n" {
+		t.Errorf("Expected whole seconds, got %q", s)
+	}
+	if timerlist[0].StartDate.Nanosecond() != 123456789 {
+		t.Error("Expected the dates themselves to be unchanged")
+	}
+	if s := other.String(); s != text {
+		t.Errorf("Expected other lists to be unaffected, got %q", s)
+	}
+}
+
+func TestLoadCSVErrorKeepsList(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z Existing\n")
+	err := timerlist.LoadCSV(strings.NewReader("StartDate,Notes\n2019-02-15T09:00:00Z,Good\nnot-a-date,Bad\n"))
+	if err == nil {
+		t.Fatal("Expected an error for an invalid StartDate")
+	}
+	if got := notesOf(timerlist); got != "Existing" {
+		t.Errorf("Expected the list to be unchanged, got %q", got)
+	}
+}
+
+func TestAddedTimersKeepListLayout(t *testing.T) {
+	var timerlist TimerList
+	if err := timerlist.LoadFromWithOptions(strings.NewReader("2019-02-15_08:00 First\n"), LoadOptions{DateLayout: "2006-01-02_15:04"}); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2019, 2, 15, 9, 0, 0, 0, time.UTC)
+	timerlist.AppendTimer(&Timer{StartDate: start, Notes: "Appended"})
+	timerlist.AddTimer(&Timer{StartDate: start, Notes: "Added"})
+	want := "2019-02-15_09:00 Added\n2019-02-15_08:00 First\n2019-02-15_09:00 Appended\n"
+	if s := timerlist.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+	timerlist.SetDateLayout("2006/01/02 15:04")
+	timerlist.AppendTimer(&Timer{StartDate: start, Notes: "Later"})
+	if s := timerlist[len(timerlist)-1].String(); s != "2019/02/15 09:00 Later" {
+		t.Errorf("Expected the new layout, got %q", s)
+	}
+}
+
+func TestAddedTimersKeepListPrecision(t *testing.T) {
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n")
+	timerlist.SetDateLayout(time.RFC3339Nano)
+	timerlist.SetDatePrecision(time.Second)
+	timerlist.AppendTimer(&Timer{StartDate: time.Date(2019, 2, 15, 9, 0, 0, 500000000, time.UTC), Notes: "Appended"})
+	if s := timerlist[1].String(); s != "2019-02-15T09:00:00Z Appended" {
+		t.Errorf("Expected the list precision, got %q", s)
+	}
+}
+
+func TestWriteToFilenameSymlink(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "dotfiles", "timer.txt")
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(target, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "timer.txt")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skip("Symlinks not supported:", err)
+	}
+	timerlist := loadTestList(t, "2019-02-15T08:00:00Z First\n")
+	if err := timerlist.WriteToFilename(link); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("Expected the symlink to be kept (%v)", err)
+	}
+	data, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != timerlist.String() {
+		t.Errorf("Expected the target to be written, got %q", data)
+	}
+	entries, err := os.ReadDir(filepath.Dir(target))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary file left behind, got %v", entries)
+	}
+}
+
+func TestTrailingCommentsStayAtEnd(t *testing.T) {
+	text := "# Monday\n2019-02-15T08:00:00Z First\n2019-02-15T09:00:00Z Second\n# end of file\n"
+	var timerlist TimerList
+	if err := timerlist.LoadFromWithOptions(strings.NewReader(text), LoadOptions{KeepComments: true}); err != nil {
+		t.Fatal(err)
+	}
+	timerlist.AppendTimer(&Timer{StartDate: time.Date(2019, 2, 15, 10, 0, 0, 0, time.UTC), Notes: "Third"})
+	timerlist.Reverse()
+	want := "2019-02-15T10:00:00Z Third\n2019-02-15T09:00:00Z Second\n# Monday\n2019-02-15T08:00:00Z First\n# end of file\n"
+	if s := timerlist.String(); s != want {
+		t.Errorf("Expected %q, got %q", want, s)
+	}
+}